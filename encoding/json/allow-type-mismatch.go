@@ -0,0 +1,1827 @@
+// Copyright 2024 Oscar Pernia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A TypeMismatch describes a JSON value that was not appropriate for a value
+// of a specific Go type, and that a [Decoder] tolerated because of
+// [Decoder.AllowTypeMismatch] instead of returning an [UnmarshalTypeError].
+type TypeMismatch struct {
+	Value  string       // description of JSON value - "bool", "array", "number -5"
+	Raw    RawMessage   // the JSON value itself
+	Type   reflect.Type // type of Go value it could not be assigned to
+	Offset int64        // mismatch occurred after reading Offset bytes
+	Struct string       // name of the struct type containing the field
+	Field  string       // the full path from root node to the field
+
+	// InputOffset is the offset in the input of the first byte of the JSON
+	// value, as reported by [Decoder.InputOffset]. Unlike Offset, which is
+	// relative to the value being decoded, it counts from the start of the
+	// stream read by the Decoder.
+	InputOffset int64
+
+	// Cause is the reason for the report, CauseTypeMismatch for all the
+	// entries but those requested with [Decoder.SetReportCaseFolding].
+	Cause Cause
+
+	// ActualKind is the kind of the JSON value in Raw, for the programs that
+	// inspect the report instead of reading Value. The type that the value
+	// was expected to have is Type.
+	ActualKind Kind
+
+	// Path is the path from root node to the value, including the indexes
+	// of the array elements it is nested in, unlike Field. It is formatted
+	// by the function given to [Decoder.SetPathFormatter], by default as in
+	// "items[2].price". With [Decoder.SetCumulativeMismatches], it is
+	// prefixed with the index of the record, as Field is.
+	Path string
+
+	// Panic is the value recovered from a panic of the UnmarshalJSON or
+	// UnmarshalText method that decoded the value, with
+	// [Decoder.SetRecoverUnmarshalPanics], and nil otherwise.
+	Panic any
+}
+
+// Error describes the mismatch, so that a single one can be used as an error,
+// as in "json: type mismatch at items[2].price: expected int, got string".
+func (m TypeMismatch) Error() string {
+	msg := "json: " + m.Cause.String()
+	if m.Path != "" {
+		msg += " at " + m.Path
+	}
+	typ := "<nil>"
+	if m.Type != nil {
+		typ = m.Type.String()
+	}
+	if m.Cause == CauseCaseFolding {
+		return msg + ": " + m.Value + " matched a field of type " + typ
+	}
+	msg += ": expected " + typ + ", got " + m.ActualKind.String()
+	if m.Panic != nil {
+		msg += fmt.Sprintf(" (panic: %v)", m.Panic)
+	}
+	return msg
+}
+
+// A PathSegment is a step in the path from the root of a JSON value to a
+// value nested in it: the JSON name of a struct field, or the index of an
+// array element. As in [TypeMismatch.Field], map keys are not part of paths.
+type PathSegment struct {
+	Key   string // name of the struct field, if not Array
+	Index int    // index of the array element, if Array
+	Array bool   // whether the segment is an array element
+}
+
+// SetPathFormatter makes the Decoder format [TypeMismatch.Path] with f, so
+// that the reports can use the path syntax of other tools, such as JSONPath.
+// f is given the segments of the path, which must not be retained, and an
+// empty slice for a mismatch of the whole value.
+//
+// Calling SetPathFormatter(nil) restores the default format, which joins the
+// field names with dots and writes array indexes in brackets, as in
+// "items[2].price". A name that is empty or has dots or brackets is quoted
+// in brackets instead, as in `items[2]["unit.price"]`.
+func (dec *Decoder) SetPathFormatter(f func(segments []PathSegment) string) {
+	dec.d.pathFormatter = f
+}
+
+// path returns the path of the value being decoded, for [TypeMismatch.Path].
+func (d *decodeState) path() string {
+	var fields []string
+	if d.errorContext != nil {
+		fields = d.errorContext.FieldStack
+	}
+	segments := d.segments[:0]
+	arrays := d.arrays
+	for i := 0; i <= len(fields); i++ {
+		for len(arrays) > 0 && arrays[0].depth == i {
+			segments = append(segments, PathSegment{Index: arrays[0].index, Array: true})
+			arrays = arrays[1:]
+		}
+		if i < len(fields) {
+			segments = append(segments, PathSegment{Key: fields[i]})
+		}
+	}
+	d.segments = segments
+	return d.formatPath(segments)
+}
+
+// recordPath returns the path last returned by path, prefixed with the index
+// of the record being decoded, for the report of SetCumulativeMismatches.
+func (d *decodeState) recordPath() string {
+	return d.formatPath(append([]PathSegment{{Index: d.record, Array: true}}, d.segments...))
+}
+
+// formatPath formats the path made of segments, with the function given to
+// SetPathFormatter if any.
+func (d *decodeState) formatPath(segments []PathSegment) string {
+	if d.pathFormatter != nil {
+		return d.pathFormatter(segments)
+	}
+	var b strings.Builder
+	for _, s := range segments {
+		if s.Array {
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(s.Index))
+			b.WriteByte(']')
+			continue
+		}
+		if s.Key == "" || strings.ContainsAny(s.Key, ".[]") {
+			b.WriteString("[" + strconv.Quote(s.Key) + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(s.Key)
+	}
+	return b.String()
+}
+
+// A Kind is the kind of a JSON value.
+type Kind int
+
+const (
+	KindInvalid Kind = iota // no JSON value
+	KindNull
+	KindBool
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindInvalid:
+		return "invalid"
+	case KindNull:
+		return "null"
+	case KindBool:
+		return "bool"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindArray:
+		return "array"
+	case KindObject:
+		return "object"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// kindOf returns the kind of the JSON value raw.
+func kindOf(raw []byte) Kind {
+	if len(raw) == 0 {
+		return KindInvalid
+	}
+	switch raw[0] {
+	case 'n':
+		return KindNull
+	case 't', 'f':
+		return KindBool
+	case '"':
+		return KindString
+	case '[':
+		return KindArray
+	case '{':
+		return KindObject
+	}
+	return KindNumber
+}
+
+// A Cause is the reason for a [TypeMismatch] report.
+type Cause int
+
+const (
+	// CauseTypeMismatch is a JSON value that was not appropriate for the Go
+	// value, and was tolerated.
+	CauseTypeMismatch Cause = iota
+
+	// CauseCaseFolding is an object key that matched the name of a struct
+	// field only case-insensitively, such as "Int" for a field tagged
+	// `json:"int"`. It is not a type mismatch: the value is decoded as usual.
+	CauseCaseFolding
+)
+
+func (c Cause) String() string {
+	switch c {
+	case CauseTypeMismatch:
+		return "type mismatch"
+	case CauseCaseFolding:
+		return "case folding"
+	}
+	return "Cause(" + strconv.Itoa(int(c)) + ")"
+}
+
+// SetReportCaseFolding controls whether the Decoder reports the object keys
+// that match a struct field only case-insensitively, as a data quality signal.
+// They are reported with cause [CauseCaseFolding] along with the type
+// mismatches, to the handler of [Decoder.SetMismatchHandler] and in
+// [Decoder.Mismatches] with [Decoder.RecordMismatches], but they are not type
+// mismatches: they are not counted by [Decoder.HadMismatch] or
+// [Decoder.SetMaxMismatches], and they are reported even without
+// [Decoder.AllowTypeMismatch].
+func (dec *Decoder) SetReportCaseFolding(on bool) { dec.d.reportCaseFolding = on }
+
+// saveCaseFolding reports the object key item, found at offset start in the
+// data, that matched the struct field of type t only case-insensitively.
+func (d *decodeState) saveCaseFolding(item []byte, t reflect.Type, start int) {
+	if d.mismatchHandler == nil && d.logger == nil && !d.recordMismatches {
+		return
+	}
+	m := TypeMismatch{
+		Value:       "object key " + string(item),
+		Raw:         bytes.Clone(item),
+		Type:        t,
+		Offset:      int64(start + 1),
+		InputOffset: d.inputOffset + int64(start),
+		Cause:       CauseCaseFolding,
+		ActualKind:  KindString,
+	}
+	if d.errorContext != nil && d.errorContext.Struct != nil {
+		m.Struct = d.errorContext.Struct.Name()
+		m.Field = strings.Join(d.errorContext.FieldStack, ".")
+	}
+	m.Path = d.path()
+	if d.mismatchHandler != nil {
+		d.mismatchHandler(m)
+	}
+	if d.logger != nil {
+		d.logMismatch(m)
+	}
+	if d.recordMismatches {
+		d.mismatches = append(d.mismatches, m)
+		if d.cumulative {
+			d.recordPaths = append(d.recordPaths, d.recordPath())
+		}
+	}
+}
+
+// typeMismatches returns the entries of report with cause CauseTypeMismatch.
+func typeMismatches(report []TypeMismatch) []TypeMismatch {
+	if !slices.ContainsFunc(report, func(m TypeMismatch) bool { return m.Cause != CauseTypeMismatch }) {
+		return report
+	}
+	var ms []TypeMismatch
+	for _, m := range report {
+		if m.Cause == CauseTypeMismatch {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// RecordMismatches causes the Decoder to record the type mismatches it
+// tolerates, so that they can be retrieved with [Decoder.Mismatches].
+// Mismatches are not recorded by default, so that decoding with
+// [Decoder.AllowTypeMismatch] alone does not allocate for them.
+func (dec *Decoder) RecordMismatches() { dec.d.recordMismatches = true }
+
+// Mismatches returns the type mismatches tolerated by the most recent call to
+// [Decoder.Decode], in the order they were found in the input, or those of
+// all the calls with [Decoder.SetCumulativeMismatches].
+// It returns nil if the value was decoded without mismatches, or if neither
+// [Decoder.RecordMismatches] nor [Decoder.SetMismatchAsError] were called.
+func (dec *Decoder) Mismatches() []TypeMismatch {
+	if dec.d.cumulative {
+		return dec.report
+	}
+	return dec.d.mismatches
+}
+
+// MismatchesUnder returns the entries of [Decoder.Mismatches] whose
+// [TypeMismatch.Path] is prefix or is under it, so that the mismatches of a
+// subtree can be handled apart from the others. A path is under prefix if it
+// continues after it with '.', '[' or '/', as the paths of the default format
+// and of common formatters given to [Decoder.SetPathFormatter] do. In prefix,
+// "[]" stands for any array index, as in "items[].price", which is under
+// "items[]" as "items[2].price" is under "items[2]" and "items".
+func (dec *Decoder) MismatchesUnder(prefix string) []TypeMismatch {
+	var ms []TypeMismatch
+	for _, m := range dec.Mismatches() {
+		if pathUnder(m.Path, prefix) {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// pathUnder reports whether path is prefix, or is under it.
+func pathUnder(path, prefix string) bool {
+	for prefix != "" {
+		i := strings.Index(prefix, "[]")
+		if i < 0 {
+			break
+		}
+		if !strings.HasPrefix(path, prefix[:i+1]) {
+			return false
+		}
+		// Skip the index of path, if any.
+		path = path[i+1:]
+		j := strings.IndexByte(path, ']')
+		if j < 0 || strings.Trim(path[:j], "0123456789") != "" {
+			return false
+		}
+		path, prefix = path[j+1:], prefix[i+2:]
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || prefix == "" || rest[0] == '.' || rest[0] == '[' || rest[0] == '/'
+}
+
+// WriteMismatchReport writes report to w as a JSON array, followed by a
+// newline, for logging the mismatches of a decoded value in a stable format.
+// Each mismatch is an object with the members:
+//
+//   - "path": the [TypeMismatch.Path] of the value
+//   - "expected": the name of the Go type, as given by [reflect.Type.String]
+//   - "actual": the kind of the JSON value, as given by [Kind.String]
+//   - "offset": the [TypeMismatch.InputOffset] of the value
+//   - "cause": the [Cause] of the report, as given by [Cause.String]
+//
+// The JSON values themselves are left out, as they may be large or hold
+// sensitive data. An empty report is written as [].
+func WriteMismatchReport(w io.Writer, report []TypeMismatch) error {
+	type entry struct {
+		Path     string `json:"path"`
+		Expected string `json:"expected"`
+		Actual   string `json:"actual"`
+		Offset   int64  `json:"offset"`
+		Cause    string `json:"cause"`
+	}
+	entries := make([]entry, len(report))
+	for i, m := range report {
+		entries[i] = entry{
+			Path:   m.Path,
+			Actual: m.ActualKind.String(),
+			Offset: m.InputOffset,
+			Cause:  m.Cause.String(),
+		}
+		if m.Type != nil {
+			entries[i].Expected = m.Type.String()
+		}
+	}
+	return NewEncoder(w).Encode(entries)
+}
+
+// SetCumulativeMismatches controls whether [Decoder.Mismatches] returns the
+// type mismatches of all the values decoded, or records, instead of only those
+// of the most recent call to [Decoder.Decode]. Mismatches are accumulated
+// until [Decoder.ResetMismatches] is called, and the [TypeMismatch.Field] and
+// [TypeMismatch.Path] of each are prefixed with the index of its record since
+// then, as in "[2].a.b", or "[2]" for a mismatch of the whole record. A path
+// formatter set with [Decoder.SetPathFormatter] is given the index as the
+// first segment. Like Mismatches, it needs [Decoder.RecordMismatches].
+func (dec *Decoder) SetCumulativeMismatches(on bool) { dec.d.cumulative = on }
+
+// ResetMismatches discards the mismatches accumulated with
+// [Decoder.SetCumulativeMismatches], and numbers the next record 0.
+func (dec *Decoder) ResetMismatches() {
+	dec.report = nil
+	dec.records = 0
+}
+
+// accumulateMismatches adds the mismatches of the record just decoded to the
+// cumulative report.
+func (dec *Decoder) accumulateMismatches() {
+	prefix := "[" + strconv.Itoa(dec.records) + "]"
+	for i, m := range dec.d.mismatches {
+		if m.Field == "" {
+			m.Field = prefix
+		} else {
+			m.Field = prefix + "." + m.Field
+		}
+		m.Path = dec.d.recordPaths[i]
+		dec.report = append(dec.report, m)
+	}
+	dec.records++
+}
+
+// HadMismatch reports whether the most recent call to [Decoder.Decode]
+// tolerated any type mismatch. Unlike [Decoder.Mismatches], it does not need
+// [Decoder.RecordMismatches].
+func (dec *Decoder) HadMismatch() bool { return dec.d.mismatchCount > 0 }
+
+// WasMismatched reports whether the most recent call to [Decoder.Decode]
+// tolerated a type mismatch for the field at path, as in [TypeMismatch.Field].
+// It tells apart a field that is zero because its value was mismatched from
+// one that is zero because it was absent, which is never a mismatch.
+// It needs [Decoder.RecordMismatches]. With [Decoder.SetCumulativeMismatches],
+// it looks for path, prefixed with a record index, in all the records.
+func (dec *Decoder) WasMismatched(path string) bool {
+	for _, m := range dec.Mismatches() {
+		if m.Field == path && m.Cause == CauseTypeMismatch {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMismatchHandler makes the Decoder call h for every type mismatch it
+// tolerates, as soon as it is found. The handler is called regardless of
+// [Decoder.RecordMismatches].
+//
+// Calling SetMismatchHandler(nil) removes the handler.
+func (dec *Decoder) SetMismatchHandler(h func(m TypeMismatch)) { dec.d.mismatchHandler = h }
+
+// SetMismatchResolver makes the Decoder call r for every type mismatch it
+// tolerates, with the path of the field, as in [TypeMismatch.Field], the type
+// of the Go value and the JSON value. If r returns true and a value assignable
+// to goType, the value is stored instead of the zero value; a nil value stores
+// the zero value. Otherwise, the Go value is set to zero, or kept in merge mode,
+// as if there were no resolver. Unlike a function registered with
+// [Decoder.RegisterCoercion], a resolved value is still a type mismatch, and
+// it is reported as such.
+//
+// Calling SetMismatchResolver(nil) removes the resolver.
+func (dec *Decoder) SetMismatchResolver(r func(path string, goType reflect.Type, raw RawMessage) (any, bool)) {
+	dec.d.mismatchResolver = r
+}
+
+// resolve stores in v the value that the mismatch resolver returns for the
+// JSON value raw, if any. It reports whether v was set.
+func (d *decodeState) resolve(raw []byte, v reflect.Value) bool {
+	if d.mismatchResolver == nil {
+		return false
+	}
+	var path string
+	if d.errorContext != nil {
+		path = strings.Join(d.errorContext.FieldStack, ".")
+	}
+	x, ok := d.mismatchResolver(path, v.Type(), RawMessage(raw))
+	if !ok {
+		return false
+	}
+	if x == nil {
+		v.SetZero()
+		return true
+	}
+	xv := reflect.ValueOf(x)
+	if !xv.Type().AssignableTo(v.Type()) {
+		return false
+	}
+	v.Set(xv)
+	return true
+}
+
+// A Logger is used by a [Decoder] to log the type mismatches it tolerates.
+type Logger interface {
+	Warnf(format string, args ...any)
+}
+
+// SetLogger makes the Decoder log every type mismatch it tolerates with
+// l.Warnf, including the path of the field and the types involved. It is
+// meant for debugging; use [Decoder.SetMismatchHandler] or
+// [Decoder.RecordMismatches] to handle mismatches programmatically.
+//
+// Calling SetLogger(nil) disables logging, which is the default.
+func (dec *Decoder) SetLogger(l Logger) { dec.d.logger = l }
+
+func (d *decodeState) logMismatch(m TypeMismatch) {
+	if m.Cause == CauseCaseFolding {
+		d.logger.Warnf("json: case folding at offset %d: %s matched Go struct field %s.%s of type %v",
+			m.Offset, m.Value, m.Struct, m.Field, m.Type)
+		return
+	}
+	if m.Struct != "" || m.Field != "" {
+		d.logger.Warnf("json: type mismatch at offset %d: cannot unmarshal %s into Go struct field %s.%s of type %v",
+			m.Offset, m.Value, m.Struct, m.Field, m.Type)
+		return
+	}
+	d.logger.Warnf("json: type mismatch at offset %d: cannot unmarshal %s into Go value of type %v",
+		m.Offset, m.Value, m.Type)
+}
+
+// SetMaxMismatches limits to n the number of type mismatches that the Decoder
+// tolerates in a single value. As soon as a value is found to have more than n
+// mismatches, [Decoder.Decode] stops decoding it and returns a
+// [*TooManyMismatchesError]. A negative n means no limit, which is the
+// default.
+func (dec *Decoder) SetMaxMismatches(n int) { dec.d.maxMismatches = n }
+
+// SetToleranceBudgetPerStruct limits to n the number of type mismatches that
+// the Decoder tolerates in the fields of each struct, as a finer-grained
+// [Decoder.SetMaxMismatches]: once a struct has n mismatches, the following
+// ones in its fields are errors, as without [Decoder.AllowTypeMismatch], on
+// the grounds that the shape of the input has likely changed. The mismatches
+// of the elements of an array field count for the struct, while those of the
+// fields of a nested struct count only for the nested struct. A negative n
+// means no limit, which is the default.
+func (dec *Decoder) SetToleranceBudgetPerStruct(n int) { dec.d.structBudget = n }
+
+// A TooManyMismatchesError is returned by [Decoder.Decode] when a value has
+// more type mismatches than allowed by [Decoder.SetMaxMismatches].
+type TooManyMismatchesError struct {
+	Limit      int            // maximum number of mismatches allowed
+	Count      int            // number of mismatches found before decoding stopped
+	Mismatches []TypeMismatch // mismatches found, if [Decoder.RecordMismatches] was called
+}
+
+func (e *TooManyMismatchesError) Error() string {
+	return "json: too many type mismatches: found " + strconv.Itoa(e.Count) + ", limit is " + strconv.Itoa(e.Limit)
+}
+
+// SetMaxDepth limits to n the nesting depth of the arrays and objects that
+// the Decoder decodes, to protect against pathological input; the top-level
+// value is at depth 1. An array or object nested deeper is skipped without
+// being decoded: with [Decoder.AllowTypeMismatch], it is a type mismatch and
+// the Go value is set to zero, and otherwise Decode returns a
+// [*MaxDepthError]. A zero or negative n means no limit other than the one of
+// the syntax checker, which is the default.
+func (dec *Decoder) SetMaxDepth(n int) { dec.d.maxDepth = max(n, 0) }
+
+// A MaxDepthError is returned by [Decoder.Decode] for an array or object
+// nested deeper than allowed by [Decoder.SetMaxDepth], when the type
+// mismatches are not tolerated.
+type MaxDepthError struct {
+	Value  string // "array" or "object"
+	Depth  int    // maximum depth allowed
+	Offset int64  // the value starts after reading Offset bytes
+}
+
+func (e *MaxDepthError) Error() string {
+	return "json: " + e.Value + " at offset " + strconv.FormatInt(e.Offset, 10) + " exceeds the max depth of " + strconv.Itoa(e.Depth)
+}
+
+// tooDeep reports whether the array or object that starts at the byte just
+// read is nested deeper than allowed by SetMaxDepth.
+func (d *decodeState) tooDeep() bool {
+	return d.maxDepth > 0 && len(d.scan.parseState) > d.maxDepth
+}
+
+// depthMismatch skips the array or object that is too deep to be decoded
+// into v, and handles it as a type mismatch if tolerated.
+func (d *decodeState) depthMismatch(value string, v reflect.Value) error {
+	off, start := d.off, d.readIndex()
+	d.skip()
+	if !d.tolerates() {
+		d.saveError(&MaxDepthError{Value: value, Depth: d.maxDepth, Offset: int64(start)})
+		return nil
+	}
+	d.valueStart = start
+	d.saveValueTypeError(value, nil, d.data[start:d.off], v, off)
+	return d.mismatchLimitErr
+}
+
+// storeExtra stores raw, the value of the object key that the struct v has no
+// field for, in its field at index, which has the "extra" option.
+func (d *decodeState) storeExtra(v reflect.Value, index []int, key string, raw []byte) {
+	for _, i := range index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					d.saveError(fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem()))
+					return
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(RawMessage(bytes.Clone(raw))))
+}
+
+// SetStringCoercion controls whether the Decoder parses JSON strings into
+// bool, integer and floating-point values, as in "123" into an int or "true"
+// into a bool. Numbers must use JSON number syntax and bools are parsed with
+// [strconv.ParseBool]. A string that cannot be parsed, or overflows the
+// target, is handled as any other type mismatch. Coercion is off by default.
+func (dec *Decoder) SetStringCoercion(on bool) { dec.d.coerceStrings = on }
+
+// SetAllNumbersAsStrings controls whether the Decoder parses JSON strings into
+// integer and floating-point values, for the producers that quote every
+// number, such as BigQuery, without a ",string" option on every field. It is
+// [Decoder.SetStringCoercion] limited to numbers: bools are not parsed, and
+// the strings "NaN", "Infinity" and "-Infinity", which such producers write
+// for the values that JSON numbers cannot represent, are parsed into floats.
+// Unquoted numbers are decoded as usual. A string that cannot be parsed, or
+// overflows the target, is handled as any other type mismatch. It is off by
+// default.
+func (dec *Decoder) SetAllNumbersAsStrings(on bool) { dec.d.numbersAsStrings = on }
+
+// coerceNumberString parses s, a quoted number, into v, as allowed by
+// SetAllNumbersAsStrings. It reports whether v was set.
+func coerceNumberString(s []byte, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return false
+	case reflect.Float32, reflect.Float64:
+		switch string(s) {
+		case "NaN":
+			v.SetFloat(math.NaN())
+			return true
+		case "Infinity":
+			v.SetFloat(math.Inf(1))
+			return true
+		case "-Infinity":
+			v.SetFloat(math.Inf(-1))
+			return true
+		}
+	}
+	return coerceString(s, v)
+}
+
+// SetDecimalComma controls whether the strings that [Decoder.SetStringCoercion]
+// and [Decoder.SetAllNumbersAsStrings] parse into integer and floating-point
+// values are read as written in the locales that use a decimal comma, with
+// optional dots between groups of three digits, as in "1.234,56" or "-0,5".
+// Such strings are then the only ones parsed into numbers: "1.5" is a type
+// mismatch, and "1.234" is parsed as 1234. It is off by default.
+func (dec *Decoder) SetDecimalComma(on bool) { dec.d.decimalComma = on }
+
+// fromDecimalComma rewrites s, a number written as SetDecimalComma allows, in
+// JSON number syntax. It reports whether s is written that way.
+func fromDecimalComma(s []byte) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	i := 0
+	if len(s) > 0 && s[0] == '-' {
+		b = append(b, '-')
+		i++
+	}
+	// Integer part, in groups of three digits but the first one.
+	digits, group, grouped := 0, 0, false
+	for ; i < len(s) && s[i] != ','; i++ {
+		c := s[i]
+		if c == '.' {
+			if group == 0 || group > 3 || grouped && group != 3 {
+				return nil, false
+			}
+			group, grouped = 0, true
+			continue
+		}
+		if c < '0' || '9' < c {
+			return nil, false
+		}
+		b = append(b, c)
+		digits++
+		group++
+	}
+	if digits == 0 || grouped && group != 3 {
+		return nil, false
+	}
+	if i == len(s) {
+		return b, true
+	}
+	// Fraction, after the comma.
+	i++
+	if i == len(s) {
+		return nil, false
+	}
+	b = append(b, '.')
+	for ; i < len(s); i++ {
+		if s[i] < '0' || '9' < s[i] {
+			return nil, false
+		}
+		b = append(b, s[i])
+	}
+	return b, true
+}
+
+// isNumberKind reports whether k is an integer or floating-point kind.
+func isNumberKind(k reflect.Kind) bool {
+	return isIntKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// SetDurationStrings controls whether the Decoder parses JSON strings into
+// [time.Duration] values with [time.ParseDuration], as in "5m" or "1h30m".
+// JSON numbers are always decoded as a count of nanoseconds. A string that
+// is not a valid duration is handled as any other type mismatch. It is off by
+// default.
+func (dec *Decoder) SetDurationStrings(on bool) { dec.d.durationStrings = on }
+
+// SetIntFromFloat controls whether the Decoder decodes JSON numbers written
+// in a floating-point form, with a fraction or an exponent, into integer
+// values when their value is an integer, as 1e3 and 1.5e1 into an int, 1000
+// and 15. By default, as with [Unmarshal], any such number is a type mismatch
+// for an integer, whatever its value. Numbers such as 1e-1, which are not
+// integers, or that overflow the integer, are always type mismatches.
+func (dec *Decoder) SetIntFromFloat(on bool) { dec.d.intFromFloat = on }
+
+// integralFloat stores the JSON number item in v, a signed or unsigned
+// integer, if its value is an integer that v can hold. It reports whether v
+// was set.
+func integralFloat(item []byte, v reflect.Value) bool {
+	// Rule out the numbers that do not fit in 64 bits before parsing them
+	// exactly, so that a large exponent cannot make the parsing expensive.
+	f, err := strconv.ParseFloat(string(item), 64)
+	if err != nil || f <= -(1<<64) || f >= 1<<64 {
+		return false
+	}
+	r, ok := new(big.Rat).SetString(string(item))
+	if !ok || !r.IsInt() {
+		return false
+	}
+	n := r.Num()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+			return false
+		}
+		v.SetInt(n.Int64())
+	default:
+		if !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+			return false
+		}
+		v.SetUint(n.Uint64())
+	}
+	return true
+}
+
+// SetAllowComments controls whether the Decoder accepts comments in its
+// input, as in relaxed configuration files. Comments are either line
+// comments, from // to the end of the line, or block comments, from /* to */,
+// and they are allowed wherever spaces are. They are off by default, as JSON
+// does not have comments. [Decoder.Token] and [Decoder.More] do not skip
+// comments, so they should not be used with input that has them.
+func (dec *Decoder) SetAllowComments(on bool) {
+	dec.scan.allowComments = on
+	dec.d.scan.allowComments = on
+}
+
+// SetAllowTrailingCommas controls whether the Decoder accepts a comma after
+// the last element of an array or object, as in [1, 2,] or {"a": 1,}, which
+// is common in hand-edited files. A comma alone, as in [,], is still a syntax
+// error. It is off by default, as JSON does not allow trailing commas. As with
+// [Decoder.SetAllowComments], [Decoder.Token] does not accept them.
+func (dec *Decoder) SetAllowTrailingCommas(on bool) {
+	dec.scan.allowTrailingCommas = on
+	dec.d.scan.allowTrailingCommas = on
+}
+
+// SetAllowLeadingZeros controls whether the Decoder accepts numbers with
+// leading zeros, as in 0123 or -007.5, which some non-compliant producers
+// write. Such a number is decoded as the decimal number without the zeros,
+// 123 or -7.5, and 00 as 0; the value given to an [Unmarshaler] or stored in
+// a [RawMessage] keeps them. It is off by default, as JSON does not allow
+// leading zeros.
+func (dec *Decoder) SetAllowLeadingZeros(on bool) {
+	dec.scan.allowLeadingZeros = on
+	dec.d.scan.allowLeadingZeros = on
+}
+
+// trimLeadingZeros returns the number item without the zeros that precede
+// another digit of its integer part.
+func trimLeadingZeros(item []byte) []byte {
+	digits := item
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	i := 0
+	for i+1 < len(digits) && digits[i] == '0' && '0' <= digits[i+1] && digits[i+1] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return item
+	}
+	if len(digits) == len(item) {
+		return digits[i:]
+	}
+	// Do not write the sign into the input.
+	return append([]byte{'-'}, digits[i:]...)
+}
+
+// SetAllowNonFiniteFloats controls whether the Decoder accepts the literals
+// NaN, Infinity and -Infinity, which some non-compliant producers write for
+// the floating-point values that JSON cannot represent. They are numbers that
+// decode into a float or an interface as [math.NaN], [math.Inf](1) and
+// [math.Inf](-1), and into any other numeric type as a type mismatch, which
+// [Decoder.AllowTypeMismatch] tolerates. It is off by default, as JSON does not
+// have these literals.
+func (dec *Decoder) SetAllowNonFiniteFloats(on bool) {
+	dec.scan.allowNonFinite = on
+	dec.d.scan.allowNonFinite = on
+}
+
+// nonFinite reports whether c can start a number because it is the first
+// byte of a literal allowed by SetAllowNonFiniteFloats.
+func (d *decodeState) nonFinite(c byte) bool {
+	return d.scan.allowNonFinite && (c == 'N' || c == 'I')
+}
+
+// SetAllowBOM controls whether the Decoder skips a UTF-8 byte order mark,
+// the bytes EF BB BF, at the start of its input, as written by some Windows
+// programs. The mark is still counted by [Decoder.InputOffset]. It is off by
+// default, as JSON text must not start with one.
+func (dec *Decoder) SetAllowBOM(on bool) { dec.allowBOM = on }
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// skipBOM skips the UTF-8 byte order mark at the start of the input, with
+// SetAllowBOM. It reports whether more input must be read first to tell
+// whether the input starts with one.
+func (dec *Decoder) skipBOM() bool {
+	if !dec.allowBOM || dec.scanned != 0 || dec.scanp != 0 {
+		return false
+	}
+	if bytes.HasPrefix(dec.buf, utf8BOM) {
+		dec.scanp = len(utf8BOM)
+		return false
+	}
+	return bytes.HasPrefix(utf8BOM, dec.buf)
+}
+
+// SetTimeLayouts makes the Decoder parse a JSON string that is not a valid
+// [time.Time], as [time.Time.UnmarshalJSON] only accepts RFC 3339, with each
+// of layouts in order, as in [time.Parse], until one of them succeeds. If none
+// does, the value is a type mismatch with [Decoder.AllowTypeMismatch], and
+// otherwise Decode returns the error of [time.Time.UnmarshalJSON], as it does
+// for values that are not strings.
+//
+// Calling SetTimeLayouts(nil) removes the layouts; with
+// [Decoder.AllowTypeMismatch], input that is not a valid time.Time is then
+// returned as an error again.
+func (dec *Decoder) SetTimeLayouts(layouts []string) {
+	dec.d.timeLayouts = nil
+	if len(layouts) > 0 {
+		dec.d.timeLayouts = slices.Clone(layouts)
+	}
+}
+
+// SetEmptyCollectionsOnMismatch controls whether a slice or map that is set
+// to zero because of a type mismatch is set to an empty, non-nil value
+// instead of nil, so that it is encoded again as [] or {} rather than null.
+// By default, as any other value, it is set to nil, consistently with the xml
+// package of this module, which leaves nil a slice that the input has no
+// element for. The elements of the slice or map are not affected: a
+// mismatched element is set to its own zero value, in both packages.
+func (dec *Decoder) SetEmptyCollectionsOnMismatch(on bool) { dec.d.emptyCollections = on }
+
+// setZero sets v, a value with a type mismatch, to its zero value, or to an
+// empty slice or map with SetEmptyCollectionsOnMismatch.
+func (d *decodeState) setZero(v reflect.Value) {
+	if d.emptyCollections {
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return
+		case reflect.Map:
+			v.Set(reflect.MakeMap(v.Type()))
+			return
+		}
+	}
+	v.SetZero()
+}
+
+// SetOverflowMismatch controls whether a JSON number that overflows its
+// numeric target, such as 1e40 into a float32 or 300 into a uint8, is a type
+// mismatch. By default it is always an error, even with
+// [Decoder.AllowTypeMismatch]; when on is true, the Decoder tolerates it as
+// any other type mismatch and sets the target to zero.
+func (dec *Decoder) SetOverflowMismatch(on bool) { dec.d.overflowMismatch = on }
+
+// SetMergeMode controls whether a value tolerated as a type mismatch keeps
+// its current contents instead of being set to zero. Combined with
+// [Decoder.AllowTypeMismatch], it allows decoding a partial or partially
+// invalid document over an already populated value, so that only the values
+// that were decoded successfully are overwritten. A map element with a type
+// mismatch leaves the map entry for its key as it was.
+func (dec *Decoder) SetMergeMode(on bool) { dec.d.mergeMode = on }
+
+// SetArraysAsIndexMaps controls whether the Decoder decodes a JSON array into
+// a map with an integer key type, such as map[int]T, as the map entries for
+// the indexes of its elements, so that [1, 2] decodes as {0: 1, 1: 2}. The
+// entries are added to the map as those of an object would be, and an index
+// that overflows the key type is an error. By default, such an array is a
+// type mismatch for the map, as for any other map.
+func (dec *Decoder) SetArraysAsIndexMaps(on bool) { dec.d.indexMaps = on }
+
+// isIntKind reports whether k is a signed or unsigned integer kind.
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// indexMap decodes an array into v, a map with an integer key type, keying
+// each element by its index. The first byte of the array ('[') has been read
+// already.
+func (d *decodeState) indexMap(v reflect.Value) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	track := d.allowTypeMismatch || d.reportCaseFolding
+	if track {
+		d.arrays = append(d.arrays, arrayIndex{depth: d.fieldDepth()})
+	}
+	kv := reflect.New(t.Key()).Elem()
+	elem := reflect.New(t.Elem()).Elem()
+	for i := 0; ; i++ {
+		// Look ahead for ] - can only happen on first iteration.
+		d.scanWhile(scanSkipSpace)
+		if d.opcode == scanEndArray {
+			break
+		}
+		if track {
+			d.arrays[len(d.arrays)-1].index = i
+		}
+
+		var overflow bool
+		if kv.CanInt() {
+			overflow = kv.OverflowInt(int64(i))
+		} else {
+			overflow = kv.OverflowUint(uint64(i))
+		}
+		if overflow {
+			d.saveError(&UnmarshalTypeError{Value: "number " + strconv.Itoa(i), Type: t.Key(), Offset: int64(d.readIndex())})
+			if err := d.value(reflect.Value{}); err != nil {
+				return err
+			}
+		} else {
+			elem.SetZero()
+			mismatchCount := d.mismatchCount
+			if err := d.value(elem); err != nil {
+				return err
+			}
+			// In merge mode, a mismatched element keeps the current entry.
+			if !d.mergeMode || d.mismatchCount == mismatchCount {
+				if kv.CanInt() {
+					kv.SetInt(int64(i))
+				} else {
+					kv.SetUint(uint64(i))
+				}
+				v.SetMapIndex(kv, elem)
+			}
+			if d.mismatchLimitErr != nil {
+				return d.mismatchLimitErr
+			}
+		}
+
+		// Next token must be , or ].
+		if d.opcode == scanSkipSpace {
+			d.scanWhile(scanSkipSpace)
+		}
+		if d.opcode == scanEndArray {
+			break
+		}
+		if d.opcode != scanArrayValue {
+			panic(phasePanicMsg)
+		}
+	}
+	if track {
+		d.arrays = d.arrays[:len(d.arrays)-1]
+	}
+	return nil
+}
+
+// SetDuplicateKeyBestMatch controls which value of a struct field is kept when
+// an object has the field more than once. By default, as with [Unmarshal],
+// each value overwrites the previous one. When on is true, the first value
+// decoded without type mismatches is kept, and the values following it are
+// skipped, so that with [Decoder.AllowTypeMismatch], {"int": 5, "int": "bad"}
+// decodes 5. Skipped values are not reported as mismatches.
+func (dec *Decoder) SetDuplicateKeyBestMatch(on bool) { dec.d.duplicateKeyBestMatch = on }
+
+// RegisterCoercion makes the Decoder call fn when a JSON value is not
+// appropriate for a value of type t, with the JSON value as its argument.
+// If fn returns a nil error and a result assignable to t, the result is
+// stored and the value is not a type mismatch. Otherwise the value is handled
+// as any other type mismatch.
+//
+// Calling RegisterCoercion(t, nil) removes the function registered for t.
+func (dec *Decoder) RegisterCoercion(t reflect.Type, fn func(raw RawMessage) (any, error)) {
+	if fn == nil {
+		delete(dec.d.coercions, t)
+		return
+	}
+	if dec.d.coercions == nil {
+		dec.d.coercions = make(map[reflect.Type]func(RawMessage) (any, error))
+	}
+	dec.d.coercions[t] = fn
+}
+
+// RegisterImpl makes the Decoder decode a JSON object into a value of the
+// interface type iface as a value of type concrete, or of type *concrete if
+// only that type implements iface, when the discriminator key of the object
+// holds discriminator, as a string or as a number. The key is "type" unless
+// set with [Decoder.SetDiscriminatorKey]. The object is decoded into the new
+// value as usual, so with [Decoder.AllowTypeMismatch] its mismatches are
+// tolerated, and the key itself is decoded as any other key, into a field of
+// the value if it has one.
+//
+// The implementations only apply to an interface value that is nil or holds a
+// value that is not a pointer. Objects whose discriminator is missing, or
+// registered for none of them, are decoded as without implementations: into
+// a map for an empty interface, and as a type mismatch for any other.
+//
+// RegisterImpl panics if iface is not an interface type, or if neither
+// concrete nor *concrete implements it. Calling RegisterImpl(iface,
+// discriminator, nil) removes the implementation registered for
+// discriminator.
+func (dec *Decoder) RegisterImpl(iface reflect.Type, discriminator string, concrete reflect.Type) {
+	if iface.Kind() != reflect.Interface {
+		panic("json: RegisterImpl of non-interface type " + iface.String())
+	}
+	if concrete == nil {
+		delete(dec.d.impls[iface], discriminator)
+		if len(dec.d.impls[iface]) == 0 {
+			delete(dec.d.impls, iface)
+		}
+		return
+	}
+	if !concrete.Implements(iface) {
+		if !reflect.PointerTo(concrete).Implements(iface) {
+			panic("json: RegisterImpl: " + concrete.String() + " does not implement " + iface.String())
+		}
+		concrete = reflect.PointerTo(concrete)
+	}
+	if dec.d.impls == nil {
+		dec.d.impls = make(map[reflect.Type]map[string]reflect.Type)
+	}
+	if dec.d.impls[iface] == nil {
+		dec.d.impls[iface] = make(map[string]reflect.Type)
+	}
+	dec.d.impls[iface][discriminator] = concrete
+}
+
+// SetDiscriminatorKey sets the object key that holds the discriminator of
+// the implementations registered with [Decoder.RegisterImpl]. It is "type" by
+// default, and calling SetDiscriminatorKey("") restores it.
+func (dec *Decoder) SetDiscriminatorKey(jsonKey string) { dec.d.discriminatorKey = jsonKey }
+
+// objectImpl decodes the object whose first byte ('{') has been read into v,
+// an interface value, as the implementation registered for its
+// discriminator, and reports whether there is one.
+func (d *decodeState) objectImpl(v reflect.Value) (bool, error) {
+	impl, ok := d.registeredImpl(v.Type())
+	if !ok {
+		return false, nil
+	}
+	err := d.object(impl)
+	v.Set(impl)
+	return true, err
+}
+
+// registeredImpl looks ahead in the object whose first byte ('{') has been
+// read for the discriminator of the implementations of the interface type t,
+// and returns a new settable value of the one it names, if any. The object is
+// left to be read.
+func (d *decodeState) registeredImpl(t reflect.Type) (reflect.Value, bool) {
+	key := d.discriminatorKey
+	if key == "" {
+		key = "type"
+	}
+	// Skipping the object changes the parse state of the scanner in place,
+	// so a copy of it with its own parse state can read the object again.
+	off, opcode, scan := d.off, d.opcode, d.scan
+	scan.parseState = slices.Clone(scan.parseState)
+	start := d.readIndex()
+	d.skip()
+	item, ok := topLevelLiteral(d.data[start:d.off], key, &d.scan)
+	d.off, d.opcode, d.scan = off, opcode, scan
+	if !ok {
+		return reflect.Value{}, false
+	}
+	discriminator := string(item)
+	if s, ok := unquote(item); ok {
+		discriminator = s
+	}
+	concrete := d.impls[t][discriminator]
+	if concrete == nil {
+		return reflect.Value{}, false
+	}
+	return reflect.New(concrete).Elem(), true
+}
+
+// coerce calls the coercion function registered for the type of v, if any,
+// and stores its result in v. It reports whether v was set.
+func (d *decodeState) coerce(raw []byte, v reflect.Value) bool {
+	fn := d.coercions[v.Type()]
+	if fn == nil || !v.CanSet() {
+		return false
+	}
+	x, err := fn(RawMessage(raw))
+	if err != nil || x == nil {
+		return false
+	}
+	xv := reflect.ValueOf(x)
+	if !xv.Type().AssignableTo(v.Type()) {
+		return false
+	}
+	v.Set(xv)
+	return true
+}
+
+// parseMismatchTypes are the types whose [Unmarshaler] or
+// [encoding.TextUnmarshaler] errors only report input that cannot be parsed,
+// so that with [Decoder.AllowTypeMismatch] such an error is handled as a type
+// mismatch and the value is set to zero.
+//
+// The URL type of net/url is not one of them, as it has no text form: a JSON
+// string is always a type mismatch for it, which [Decoder.RegisterCoercion]
+// can turn into a parsed URL.
+var parseMismatchTypes = map[reflect.Type]bool{
+	reflect.TypeFor[big.Int]():        true,
+	reflect.TypeFor[big.Float]():      true,
+	reflect.TypeFor[big.Rat]():        true,
+	reflect.TypeFor[net.IP]():         true,
+	reflect.TypeFor[netip.Addr]():     true,
+	reflect.TypeFor[netip.AddrPort](): true,
+	reflect.TypeFor[netip.Prefix]():   true,
+}
+
+// tolerantParse returns the value that recv, an [Unmarshaler] or
+// [encoding.TextUnmarshaler] found by indirect, points to if it must be
+// decoded with [decodeState.unmarshalParsed]: if its errors are tolerated
+// type mismatches, as for the math/big types and the embedded pointers of
+// embeddedUnmarshaler, or if it is a [time.Time] and the decoder has time
+// layouts.
+func (d *decodeState) tolerantParse(recv any) (reflect.Value, bool) {
+	if !d.lenient {
+		return reflect.Value{}, false
+	}
+	embedded := d.tolerateUnmarshaler
+	d.tolerateUnmarshaler = false
+	rv := reflect.ValueOf(recv)
+	if rv.Kind() != reflect.Pointer {
+		return reflect.Value{}, false
+	}
+	t := rv.Type().Elem()
+	if (parseMismatchTypes[t] || embedded) && d.tolerates() || t == timeType && d.timeLayouts != nil {
+		return rv.Elem(), true
+	}
+	return reflect.Value{}, false
+}
+
+// quotedMismatch saves a type mismatch for v, if tolerated, and reports
+// whether it did, when the value of a field with the ",string" option is not
+// a string holding a literal appropriate for v. The value is the one that
+// starts at d.valueStart and was just read.
+func (d *decodeState) quotedMismatch(v reflect.Value) bool {
+	if !v.IsValid() || !d.tolerates() {
+		return false
+	}
+	raw := d.data[d.valueStart:d.readIndex()]
+	d.saveValueTypeError(kindOf(raw).String(), nil, raw, v, d.readIndex())
+	return true
+}
+
+// nilEmbeddedUnmarshaler reports whether u, an [Unmarshaler] found by
+// indirect, is a struct that embeds a nil pointer to an Unmarshaler. The
+// method has likely been promoted from that pointer, so it would be called
+// with a nil receiver; with tolerance, the struct is decoded field by field
+// instead.
+func (d *decodeState) nilEmbeddedUnmarshaler(u Unmarshaler) bool {
+	if !d.tolerates() {
+		return false
+	}
+	v := reflect.ValueOf(u)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	v = v.Elem()
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Pointer && f.Type.Implements(unmarshalerType) && v.Field(i).IsNil() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRecoverUnmarshalPanics controls whether the Decoder recovers the panics
+// of the UnmarshalJSON and UnmarshalText methods it calls, so that a method
+// that panics on unexpected input does not crash the program that decodes
+// untrusted data. A panic is handled as a type mismatch of the value that the
+// method was decoding, which is set to its zero value, with the recovered
+// value in [TypeMismatch.Panic]. Without [Decoder.AllowTypeMismatch], it is
+// returned as an [UnmarshalTypeError]. It is off by default.
+func (dec *Decoder) SetRecoverUnmarshalPanics(on bool) { dec.d.recoverPanics = on }
+
+// callUnmarshaler calls the UnmarshalJSON method of recv with raw, or its
+// UnmarshalText method with text if text is not nil, to decode into v, or
+// into the value that recv points to if v is the zero Value. With
+// SetRecoverUnmarshalPanics, a panic of the method is recovered and saved as
+// a type error for v, and recovered is true.
+func (d *decodeState) callUnmarshaler(recv any, v reflect.Value, value string, raw, text []byte, offset int) (recovered bool, err error) {
+	if d.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				if !v.IsValid() {
+					v = reflect.ValueOf(recv)
+					if v.Kind() == reflect.Pointer {
+						v = v.Elem()
+					}
+				}
+				d.unmarshalPanic = r
+				d.saveValueTypeError(value, nil, raw, v, offset)
+				d.unmarshalPanic = nil
+				recovered, err = true, d.mismatchLimitErr
+			}
+		}()
+	}
+	if text != nil {
+		return false, recv.(encoding.TextUnmarshaler).UnmarshalText(text)
+	}
+	return false, recv.(Unmarshaler).UnmarshalJSON(raw)
+}
+
+// embeddedUnmarshaler reports whether v, the value of the field f of the
+// struct type t, is a tolerated embedded pointer to an [Unmarshaler]. The
+// errors of its UnmarshalJSON are then type mismatches that set it to nil.
+func (d *decodeState) embeddedUnmarshaler(t reflect.Type, f *field, v reflect.Value) bool {
+	if f == nil || !v.IsValid() || v.Kind() != reflect.Pointer || !v.Type().Implements(unmarshalerType) || !d.tolerates() {
+		return false
+	}
+	return t.FieldByIndex(f.index).Anonymous
+}
+
+// embeddedValue decodes the value of a field into v, for which
+// embeddedUnmarshaler reports true. If its UnmarshalJSON fails, v is set to
+// nil, unless it was not nil already in merge mode.
+func (d *decodeState) embeddedValue(v reflect.Value) error {
+	mismatchCount := d.mismatchCount
+	wasNil := v.IsNil()
+	d.tolerateUnmarshaler = true
+	err := d.value(v)
+	d.tolerateUnmarshaler = false
+	if err != nil {
+		return err
+	}
+	if d.mismatchCount > mismatchCount && (wasNil || !d.mergeMode) {
+		v.SetZero()
+	}
+	return nil
+}
+
+// unmarshalParsed decodes the JSON value raw into v, a value returned by
+// tolerantParse, through its UnmarshalJSON method, or through its
+// UnmarshalText method with text. The value is decoded into a fresh value
+// first, so that v is left untouched when the input cannot be parsed.
+// If it cannot, a [time.Time] is parsed with the decoder's time layouts, and
+// otherwise the error is a type mismatch if tolerated, or returned.
+func (d *decodeState) unmarshalParsed(v reflect.Value, value string, raw, text []byte, offset int) error {
+	fresh := reflect.New(v.Type())
+	recovered, err := d.callUnmarshaler(fresh.Interface(), v, value, raw, text, offset)
+	if recovered {
+		return err
+	}
+	if err != nil && v.Type() == timeType {
+		if s, ok := unquote(raw); ok {
+			for _, layout := range d.timeLayouts {
+				t, perr := time.Parse(layout, s)
+				if perr == nil {
+					fresh.Elem().Set(reflect.ValueOf(t))
+					err = nil
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		if !d.tolerates() {
+			return err
+		}
+		d.saveValueTypeError(value, nil, raw, v, offset)
+		return d.mismatchLimitErr
+	}
+	v.Set(fresh.Elem())
+	return nil
+}
+
+// OmitPaths makes the Encoder leave out the struct fields at the paths of the
+// given type mismatches, so that a value decoded with
+// [Decoder.AllowTypeMismatch] can be encoded again without the zero values
+// that replaced the mismatched ones. A path is matched against the JSON names
+// of the enclosing struct fields, as in [TypeMismatch.Field]; as it does not
+// include array indexes or map keys, the field is omitted from every element
+// of an array and every value of a map. The mismatch of a map value that is
+// not a struct is at the path of the map, so it omits the whole map.
+//
+// Calling OmitPaths(nil) makes the Encoder encode all fields again.
+func (enc *Encoder) OmitPaths(report []TypeMismatch) { enc.omitPaths = fieldPaths(report) }
+
+// NullPaths makes the Encoder encode the struct fields at the paths of the
+// given type mismatches as null, so that a value decoded with
+// [Decoder.AllowTypeMismatch] can be encoded again with the values that could
+// not be decoded marked as unknown, instead of the zero values that replaced
+// them. Paths are matched as with [Encoder.OmitPaths], which takes precedence
+// for a field at the paths of both. The fields are encoded as null even with
+// the omitempty option.
+//
+// Calling NullPaths(nil) makes the Encoder encode all fields again.
+func (enc *Encoder) NullPaths(report []TypeMismatch) { enc.nullPaths = fieldPaths(report) }
+
+// fieldPaths returns the set of the fields of the mismatches in report, or nil
+// if there are none.
+func fieldPaths(report []TypeMismatch) map[string]bool {
+	var paths map[string]bool
+	for _, m := range report {
+		if m.Field == "" || m.Cause != CauseTypeMismatch {
+			continue
+		}
+		if paths == nil {
+			paths = make(map[string]bool)
+		}
+		paths[m.Field] = true
+	}
+	return paths
+}
+
+// SetMismatchAsError controls whether [Decoder.Decode] returns a
+// [*MismatchError] after decoding a value with type mismatches tolerated by
+// [Decoder.AllowTypeMismatch]. The value is decoded completely either way, so
+// the error can be ignored by callers that are not interested in it.
+func (dec *Decoder) SetMismatchAsError(on bool) { dec.d.mismatchAsError = on }
+
+// A MismatchError is returned by [Decoder.Decode] after
+// [Decoder.SetMismatchAsError] when the value was decoded completely, but
+// with type mismatches. It wraps an [*UnmarshalTypeError] for each of them.
+type MismatchError struct {
+	Mismatches []TypeMismatch // the tolerated mismatches, in input order
+}
+
+func (e *MismatchError) Error() string {
+	first := strings.TrimPrefix(e.unmarshalTypeError(0).Error(), "json: ")
+	if len(e.Mismatches) == 1 {
+		return "json: tolerated type mismatch: " + first
+	}
+	return "json: tolerated " + strconv.Itoa(len(e.Mismatches)) + " type mismatches, the first: " + first
+}
+
+// Unwrap returns the mismatches as an [*UnmarshalTypeError] each, the errors
+// that decoding would have returned without [Decoder.AllowTypeMismatch].
+func (e *MismatchError) Unwrap() []error {
+	errs := make([]error, len(e.Mismatches))
+	for i := range e.Mismatches {
+		errs[i] = e.unmarshalTypeError(i)
+	}
+	return errs
+}
+
+func (e *MismatchError) unmarshalTypeError(i int) *UnmarshalTypeError {
+	m := e.Mismatches[i]
+	return &UnmarshalTypeError{Value: m.Value, Type: m.Type, Offset: m.Offset, Struct: m.Struct, Field: m.Field}
+}
+
+// ToleratePaths restricts [Decoder.AllowTypeMismatch] to the fields at the
+// given paths, and the values nested in them; type mismatches anywhere else
+// are errors. A path is written as in [TypeMismatch.Field], with "[]" after a
+// field for any element of its array, as in "addr.zip" or "items[].price".
+// The elements of a top-level array are written as "[]".
+//
+// Calling ToleratePaths with no paths removes the restriction.
+func (dec *Decoder) ToleratePaths(paths ...string) {
+	dec.d.toleratePaths = slices.Clone(paths)
+	if len(paths) == 0 {
+		dec.d.toleratePaths = nil
+	}
+}
+
+// An arrayIndex is the position in an array being decoded.
+type arrayIndex struct {
+	depth int // number of struct fields being decoded outside the array
+	index int // index of the element being decoded
+}
+
+// fieldDepth returns the number of struct fields being decoded.
+func (d *decodeState) fieldDepth() int {
+	if d.errorContext == nil {
+		return 0
+	}
+	return len(d.errorContext.FieldStack)
+}
+
+// anyLenient reports whether any of the settings that change how values are
+// stored is set. Its result is kept in d.lenient for each value decoded, so
+// that decoding without them skips their checks.
+func (d *decodeState) anyLenient() bool {
+	return d.allowTypeMismatch || d.coerceStrings || d.numbersAsStrings ||
+		d.decimalComma || d.recoverPanics || d.durationStrings ||
+		d.intFromFloat || d.reportCaseFolding || d.emptyCollections ||
+		d.timeLayouts != nil || d.overflowMismatch || d.mergeMode ||
+		d.indexMaps || d.duplicateKeyBestMatch || d.coercions != nil ||
+		d.impls != nil || d.maxDepth > 0
+}
+
+// tolerates reports whether a type mismatch for the value being decoded is
+// tolerated.
+func (d *decodeState) tolerates() bool {
+	if !d.allowTypeMismatch {
+		return false
+	}
+	if n := len(d.structMismatches); n > 0 && d.structMismatches[n-1] >= d.structBudget {
+		return false
+	}
+	if d.toleratePaths == nil {
+		return true
+	}
+	path := d.pathPattern()
+	for _, p := range d.toleratePaths {
+		if path == p || strings.HasPrefix(path, p) && (path[len(p)] == '.' || path[len(p)] == '[') {
+			return true
+		}
+	}
+	return false
+}
+
+// pathPattern returns the path of the value being decoded, as accepted by
+// [Decoder.ToleratePaths].
+func (d *decodeState) pathPattern() string {
+	var fields []string
+	if d.errorContext != nil {
+		fields = d.errorContext.FieldStack
+	}
+	var b strings.Builder
+	arrays := d.arrays
+	for i := 0; i <= len(fields); i++ {
+		for len(arrays) > 0 && arrays[0].depth == i {
+			b.WriteString("[]")
+			arrays = arrays[1:]
+		}
+		if i < len(fields) {
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(fields[i])
+		}
+	}
+	return b.String()
+}
+
+// SetStructuralErrorHandler makes [Decoder.Decode] pass every structural error
+// it finds to h, and return the error returned by h in its place.
+// Structural errors are the ones that make the input not a well-formed stream
+// of JSON values: a [*SyntaxError] or [io.ErrUnexpectedEOF]. Type mismatches
+// and errors returned by the underlying reader are never passed to h.
+//
+// Calling SetStructuralErrorHandler(nil) removes the handler.
+func (dec *Decoder) SetStructuralErrorHandler(h func(err error) error) {
+	dec.structuralErrorHandler = h
+}
+
+// structuralError returns err, or the error returned by the structural error
+// handler if err is a structural error.
+func (dec *Decoder) structuralError(err error) error {
+	if dec.structuralErrorHandler == nil {
+		return err
+	}
+	var serr *SyntaxError
+	if errors.As(err, &serr) || err == io.ErrUnexpectedEOF {
+		return dec.structuralErrorHandler(err)
+	}
+	return err
+}
+
+// A Policy selects how a [Decoder] handles a JSON value that is not
+// appropriate for the Go value it is decoded into.
+type Policy int
+
+const (
+	// PolicyStrict reports type mismatches as an [UnmarshalTypeError].
+	PolicyStrict Policy = iota
+
+	// PolicyAllowTypeMismatch tolerates type mismatches, as
+	// [Decoder.AllowTypeMismatch] does.
+	PolicyAllowTypeMismatch
+)
+
+// SetVersionField makes the Decoder choose the [Policy] used for each value
+// based on a version field of the value. Before decoding a JSON object, the
+// Decoder looks for the top-level key jsonKey, and if its value is a string
+// or a number whose text is a key of policies, the corresponding policy is
+// applied to the whole value. Values without the key, or with a version not
+// present in policies, are decoded according to [Decoder.AllowTypeMismatch].
+//
+// Calling SetVersionField("", nil) disables the version lookup.
+func (dec *Decoder) SetVersionField(jsonKey string, policies map[string]Policy) {
+	dec.versionField = jsonKey
+	dec.versionPolicies = policies
+}
+
+// versionPolicy returns the policy configured for the version found in data,
+// a complete and valid JSON value.
+func (dec *Decoder) versionPolicy(data []byte) (Policy, bool) {
+	if dec.versionField == "" {
+		return 0, false
+	}
+	item, ok := topLevelLiteral(data, dec.versionField, &dec.d.scan)
+	if !ok {
+		return 0, false
+	}
+	version := string(item)
+	if s, ok := unquote(item); ok {
+		version = s
+	}
+	p, ok := dec.versionPolicies[version]
+	return p, ok
+}
+
+// topLevelLiteral returns the literal stored in the top-level key of data,
+// which must be a complete and valid JSON value, with the relaxed syntax that
+// scan allows. It reports false if data is not an object, or if key is missing
+// or holds an array or object.
+func topLevelLiteral(data []byte, key string, scan *scanner) ([]byte, bool) {
+	var d decodeState
+	d.init(data)
+	d.scan.allowComments = scan.allowComments
+	d.scan.allowTrailingCommas = scan.allowTrailingCommas
+	d.scan.allowLeadingZeros = scan.allowLeadingZeros
+	d.scan.allowNonFinite = scan.allowNonFinite
+	d.scan.reset()
+	d.scanWhile(scanSkipSpace)
+	if d.opcode != scanBeginObject {
+		return nil, false
+	}
+	for {
+		// Read opening " of string key or closing }.
+		d.scanWhile(scanSkipSpace)
+		if d.opcode == scanEndObject {
+			return nil, false
+		}
+		if d.opcode != scanBeginLiteral {
+			panic(phasePanicMsg)
+		}
+
+		// Read key.
+		start := d.readIndex()
+		d.rescanLiteral()
+		k, ok := unquoteBytes(d.data[start:d.readIndex()])
+		if !ok {
+			panic(phasePanicMsg)
+		}
+
+		// Read : before value.
+		if d.opcode == scanSkipSpace {
+			d.scanWhile(scanSkipSpace)
+		}
+		if d.opcode != scanObjectKey {
+			panic(phasePanicMsg)
+		}
+		d.scanWhile(scanSkipSpace)
+
+		if string(k) == key {
+			if d.opcode != scanBeginLiteral {
+				return nil, false
+			}
+			start := d.readIndex()
+			d.rescanLiteral()
+			return d.data[start:d.readIndex()], true
+		}
+		if err := d.value(reflect.Value{}); err != nil {
+			return nil, false
+		}
+
+		// Next token must be , or }.
+		if d.opcode == scanSkipSpace {
+			d.scanWhile(scanSkipSpace)
+		}
+		if d.opcode == scanEndObject {
+			return nil, false
+		}
+		if d.opcode != scanObjectValue {
+			panic(phasePanicMsg)
+		}
+	}
+}
+
+// Validate reads the next JSON-encoded value from its input and decodes it
+// as [Decoder.Decode] would decode it into v, tolerating type mismatches, but
+// without modifying v. It returns the type mismatches found, which is nil if
+// the value fits v, and any other error. v must be a non-nil pointer.
+func (dec *Decoder) Validate(v any) ([]TypeMismatch, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	allow, record := dec.d.allowTypeMismatch, dec.d.recordMismatches
+	dec.d.allowTypeMismatch, dec.d.recordMismatches = true, true
+	defer func() {
+		dec.d.allowTypeMismatch, dec.d.recordMismatches = allow, record
+	}()
+	err := dec.Decode(reflect.New(rv.Type().Elem()).Interface())
+	return dec.d.mismatches, err
+}
+
+// DecodeValid parses the JSON-encoded data into a new value of type T, as
+// [Unmarshal] does, but tolerating type mismatches as a [Decoder] does after
+// calling [Decoder.AllowTypeMismatch]. It returns the decoded value together
+// with the mismatches that were tolerated, which is nil if there were none.
+func DecodeValid[T any](data []byte) (T, []TypeMismatch, error) {
+	var v T
+	report, err := decodeTolerant(data, &v)
+	return v, report, err
+}
+
+// DiffStrictTolerant decodes data into v as [DecodeValid] does, tolerating
+// type mismatches, and reports what the tolerance repaired: it also decodes
+// data into a new value as [Unmarshal] does, and returns the mismatches of v
+// only if that fails. It returns nil if data can be decoded strictly, and an
+// error if it cannot be decoded even with tolerance. It is meant for
+// debugging, to find out what tolerance masks in some input.
+func DiffStrictTolerant(data []byte, v any) ([]TypeMismatch, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	report, err := decodeTolerant(data, v)
+	if err != nil {
+		return report, err
+	}
+	if Unmarshal(data, reflect.New(rv.Type().Elem()).Interface()) == nil {
+		return nil, nil
+	}
+	return report, nil
+}
+
+// decodeTolerant decodes data into v with all the type mismatches tolerated,
+// and returns them.
+func decodeTolerant(data []byte, v any) ([]TypeMismatch, error) {
+	var d decodeState
+	if err := checkValid(data, &d.scan); err != nil {
+		return nil, err
+	}
+	d.init(data)
+	d.allowTypeMismatch = true
+	d.recordMismatches = true
+	d.maxMismatches = -1
+	d.structBudget = -1
+	err := d.unmarshal(v)
+	return d.mismatches, err
+}
+
+// A LenientConfig holds the settings of a [Decoder] that make it accept input
+// that strict decoding rejects, so that a lenient profile can be defined once
+// and applied to many decoders with [Decoder.ApplyConfig]. Each field
+// corresponds to the Decoder method of the same name, except VersionField and
+// VersionPolicies, which are the arguments of [Decoder.SetVersionField].
+// MaxMismatches and ToleranceBudgetPerStruct point to the argument of their
+// method, with the same meaning, so that a limit of zero mismatches can be told
+// apart from the lack of a limit in the zero LenientConfig.
+//
+// The settings that are functions or registrations are not part of a
+// LenientConfig, and are not carried over to the decoders it configures:
+// those of [Decoder.SetMismatchHandler], [Decoder.SetMismatchResolver],
+// [Decoder.SetLogger], [Decoder.SetPathFormatter], [Decoder.RegisterCoercion]
+// and [Decoder.RegisterImpl] must be made on each Decoder.
+//
+// Unlike a Decoder, which must not be used by several goroutines at once, a
+// LenientConfig is only read by the decoders it configures, so that it can be
+// shared by goroutines that each create their own with
+// [LenientConfig.NewDecoder], as long as it is not modified meanwhile.
+type LenientConfig struct {
+	AllowTypeMismatch          bool
+	RecordMismatches           bool
+	CumulativeMismatches       bool
+	MismatchAsError            bool
+	MaxMismatches              *int // if nil, no limit
+	ToleranceBudgetPerStruct   *int // if nil, no limit
+	MaxDepth                   int  // zero or negative means no limit
+	ToleratePaths              []string
+	VersionField               string
+	VersionPolicies            map[string]Policy
+	StringCoercion             bool
+	AllNumbersAsStrings        bool
+	RecoverUnmarshalPanics     bool
+	DecimalComma               bool
+	DurationStrings            bool
+	IntFromFloat               bool
+	OverflowMismatch           bool
+	MergeMode                  bool
+	DuplicateKeyBestMatch      bool
+	ArraysAsIndexMaps          bool
+	EmptyCollectionsOnMismatch bool
+	ReportCaseFolding          bool
+	DiscriminatorKey           string
+	TimeLayouts                []string
+	AllowComments              bool
+	AllowTrailingCommas        bool
+	AllowLeadingZeros          bool
+	AllowNonFiniteFloats       bool
+	AllowBOM                   bool
+}
+
+// Config returns the settings of the Decoder that a [LenientConfig] holds, so
+// that a decoder configured once can be used as the base of others: applying
+// the result to a new Decoder with [Decoder.ApplyConfig] makes it decode as
+// dec does, but for the functions and registrations that a LenientConfig
+// does not hold. The result does not share memory with dec.
+func (dec *Decoder) Config() LenientConfig {
+	return LenientConfig{
+		AllowTypeMismatch:          dec.d.allowTypeMismatch,
+		RecordMismatches:           dec.d.recordMismatches,
+		CumulativeMismatches:       dec.d.cumulative,
+		MismatchAsError:            dec.d.mismatchAsError,
+		MaxMismatches:              limit(dec.d.maxMismatches),
+		ToleranceBudgetPerStruct:   limit(dec.d.structBudget),
+		MaxDepth:                   dec.d.maxDepth,
+		ToleratePaths:              slices.Clone(dec.d.toleratePaths),
+		VersionField:               dec.versionField,
+		VersionPolicies:            maps.Clone(dec.versionPolicies),
+		StringCoercion:             dec.d.coerceStrings,
+		AllNumbersAsStrings:        dec.d.numbersAsStrings,
+		RecoverUnmarshalPanics:     dec.d.recoverPanics,
+		DecimalComma:               dec.d.decimalComma,
+		DurationStrings:            dec.d.durationStrings,
+		IntFromFloat:               dec.d.intFromFloat,
+		OverflowMismatch:           dec.d.overflowMismatch,
+		MergeMode:                  dec.d.mergeMode,
+		DuplicateKeyBestMatch:      dec.d.duplicateKeyBestMatch,
+		ArraysAsIndexMaps:          dec.d.indexMaps,
+		EmptyCollectionsOnMismatch: dec.d.emptyCollections,
+		ReportCaseFolding:          dec.d.reportCaseFolding,
+		DiscriminatorKey:           dec.d.discriminatorKey,
+		TimeLayouts:                slices.Clone(dec.d.timeLayouts),
+		AllowComments:              dec.d.scan.allowComments,
+		AllowTrailingCommas:        dec.d.scan.allowTrailingCommas,
+		AllowLeadingZeros:          dec.d.scan.allowLeadingZeros,
+		AllowNonFiniteFloats:       dec.d.scan.allowNonFinite,
+		AllowBOM:                   dec.allowBOM,
+	}
+}
+
+// limit returns the mismatch limit n as held by a LenientConfig.
+func limit(n int) *int {
+	if n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// NewDecoder returns a new Decoder that reads from r, configured with cfg as
+// by [Decoder.ApplyConfig]. The Decoder does not share memory with cfg.
+func (cfg LenientConfig) NewDecoder(r io.Reader) *Decoder {
+	dec := NewDecoder(r)
+	dec.ApplyConfig(cfg)
+	return dec
+}
+
+// ApplyConfig configures the Decoder with cfg. Every setting of cfg is
+// applied, so the fields that are false turn off the corresponding setting
+// even if it was turned on before.
+func (dec *Decoder) ApplyConfig(cfg LenientConfig) {
+	dec.d.allowTypeMismatch = cfg.AllowTypeMismatch
+	dec.d.recordMismatches = cfg.RecordMismatches
+	dec.SetCumulativeMismatches(cfg.CumulativeMismatches)
+	dec.SetMismatchAsError(cfg.MismatchAsError)
+	dec.SetMaxMismatches(-1)
+	if cfg.MaxMismatches != nil {
+		dec.SetMaxMismatches(*cfg.MaxMismatches)
+	}
+	dec.SetToleranceBudgetPerStruct(-1)
+	if cfg.ToleranceBudgetPerStruct != nil {
+		dec.SetToleranceBudgetPerStruct(*cfg.ToleranceBudgetPerStruct)
+	}
+	dec.SetMaxDepth(cfg.MaxDepth)
+	dec.ToleratePaths(cfg.ToleratePaths...)
+	dec.SetVersionField(cfg.VersionField, maps.Clone(cfg.VersionPolicies))
+	dec.SetStringCoercion(cfg.StringCoercion)
+	dec.SetAllNumbersAsStrings(cfg.AllNumbersAsStrings)
+	dec.SetRecoverUnmarshalPanics(cfg.RecoverUnmarshalPanics)
+	dec.SetDecimalComma(cfg.DecimalComma)
+	dec.SetDurationStrings(cfg.DurationStrings)
+	dec.SetIntFromFloat(cfg.IntFromFloat)
+	dec.SetOverflowMismatch(cfg.OverflowMismatch)
+	dec.SetMergeMode(cfg.MergeMode)
+	dec.SetDuplicateKeyBestMatch(cfg.DuplicateKeyBestMatch)
+	dec.SetArraysAsIndexMaps(cfg.ArraysAsIndexMaps)
+	dec.SetEmptyCollectionsOnMismatch(cfg.EmptyCollectionsOnMismatch)
+	dec.SetReportCaseFolding(cfg.ReportCaseFolding)
+	dec.SetDiscriminatorKey(cfg.DiscriminatorKey)
+	dec.SetTimeLayouts(cfg.TimeLayouts)
+	dec.SetAllowComments(cfg.AllowComments)
+	dec.SetAllowTrailingCommas(cfg.AllowTrailingCommas)
+	dec.SetAllowLeadingZeros(cfg.AllowLeadingZeros)
+	dec.SetAllowNonFiniteFloats(cfg.AllowNonFiniteFloats)
+	dec.SetAllowBOM(cfg.AllowBOM)
+}
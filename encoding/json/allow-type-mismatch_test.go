@@ -15,10 +15,27 @@
 package json
 
 import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"maps"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestAllowTypeMismatchDecode(t *testing.T) {
@@ -220,3 +237,5020 @@ func TestAllowTypeMismatchDecode(t *testing.T) {
 	}
 
 }
+
+func TestAllowTypeMismatchReset(t *testing.T) {
+	type T struct {
+		Int    int    `json:"int"`
+		String string `json:"string"`
+	}
+
+	dec := NewDecoder(strings.NewReader(`{"int": "MISMATCHED_TYPE", "string": "test"}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.UseNumber()
+
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := (T{String: "test"}); got != want {
+		t.Fatalf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	mismatches := dec.Mismatches()
+	if len(mismatches) != 1 {
+		t.Fatalf("len(Mismatches()) = %d, want 1", len(mismatches))
+	}
+	if m := mismatches[0]; m.Field != "int" || m.Value != "string" || m.Type != reflect.TypeFor[int]() {
+		t.Fatalf("Mismatches()[0] = %+v, want mismatch of string into int field", m)
+	}
+
+	dec.Reset(strings.NewReader(`{"int": 123, "string": 456} {"any": 1}`))
+	if m := dec.Mismatches(); m != nil {
+		t.Fatalf("Mismatches() after Reset = %v, want nil", m)
+	}
+
+	got = T{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode after Reset error: %v", err)
+	}
+	if want := (T{Int: 123}); got != want {
+		t.Fatalf("Decode after Reset:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	mismatches = dec.Mismatches()
+	if len(mismatches) != 1 {
+		t.Fatalf("len(Mismatches()) after Reset = %d, want 1", len(mismatches))
+	}
+	if m := mismatches[0]; m.Field != "string" || m.Value != "number" || m.Type != reflect.TypeFor[string]() {
+		t.Fatalf("Mismatches()[0] after Reset = %+v, want mismatch of number into string field", m)
+	}
+
+	// UseNumber must survive the Reset.
+	var v map[string]any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode after Reset error: %v", err)
+	}
+	if _, ok := v["any"].(Number); !ok {
+		t.Fatalf("Decode after Reset: got %T, want Number", v["any"])
+	}
+	if m := dec.Mismatches(); m != nil {
+		t.Fatalf("Mismatches() = %v, want nil", m)
+	}
+}
+
+func TestStructuralErrorHandler(t *testing.T) {
+	type T struct {
+		Int int `json:"int"`
+	}
+
+	errReplaced := errors.New("replaced structural error")
+
+	testCases := []struct {
+		CaseName
+
+		input       string
+		strict      bool
+		wantHandled bool
+	}{
+		{
+			CaseName:    Name("SyntaxError"),
+			input:       `{"int": 1,}`,
+			wantHandled: true,
+		},
+		{
+			CaseName:    Name("UnexpectedEOF"),
+			input:       `{"int": 1`,
+			wantHandled: true,
+		},
+		{
+			CaseName:    Name("TypeError"),
+			input:       `{"int": "MISMATCHED_TYPE"}`,
+			strict:      true,
+			wantHandled: false,
+		},
+		{
+			CaseName:    Name("EOF"),
+			input:       ``,
+			wantHandled: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			if !tc.strict {
+				dec.AllowTypeMismatch()
+			}
+
+			var handled error
+			dec.SetStructuralErrorHandler(func(err error) error {
+				handled = err
+				return fmt.Errorf("%w: %w", errReplaced, err)
+			})
+
+			var v T
+			err := dec.Decode(&v)
+			if err == nil {
+				t.Fatalf("%s: Decode error: got nil, want non-nil", tc.Where)
+			}
+			if tc.wantHandled {
+				if handled == nil {
+					t.Fatalf("%s: structural error handler not called for %v", tc.Where, err)
+				}
+				if !errors.Is(err, errReplaced) {
+					t.Fatalf("%s: Decode error:\n\tgot:  %v\n\twant: error returned by handler", tc.Where, err)
+				}
+				if !errors.Is(err, handled) {
+					t.Fatalf("%s: Decode error does not wrap the original error %v", tc.Where, handled)
+				}
+			} else if handled != nil {
+				t.Fatalf("%s: structural error handler called for non-structural error %v", tc.Where, handled)
+			}
+		})
+	}
+}
+
+func TestSetVersionField(t *testing.T) {
+	type T struct {
+		Version string `json:"version"`
+		Int     int    `json:"int"`
+	}
+
+	policies := map[string]Policy{
+		"1": PolicyAllowTypeMismatch,
+		"2": PolicyStrict,
+	}
+
+	testCases := []struct {
+		CaseName
+
+		input             string
+		allowTypeMismatch bool
+		want              T
+		wantErr           bool
+	}{
+		{
+			CaseName: Name("LenientVersion"),
+			input:    `{"version": "1", "int": "MISMATCHED_TYPE"}`,
+			want:     T{Version: "1"},
+		},
+		{
+			CaseName: Name("StrictVersion"),
+			input:    `{"version": "2", "int": "MISMATCHED_TYPE"}`,
+			want:     T{Version: "2"},
+			wantErr:  true,
+		},
+		{
+			CaseName:          Name("StrictVersion_OverridesAllowTypeMismatch"),
+			input:             `{"int": "MISMATCHED_TYPE", "version": "2"}`,
+			allowTypeMismatch: true,
+			want:              T{Version: "2"},
+			wantErr:           true,
+		},
+		{
+			CaseName: Name("NumericVersion"),
+			input:    `{"nested": {"version": "2"}, "version": 1, "int": "MISMATCHED_TYPE"}`,
+			want:     T{},
+		},
+		{
+			CaseName: Name("UnknownVersion"),
+			input:    `{"version": "3", "int": "MISMATCHED_TYPE"}`,
+			want:     T{Version: "3"},
+			wantErr:  true,
+		},
+		{
+			CaseName:          Name("MissingVersion"),
+			input:             `{"int": "MISMATCHED_TYPE"}`,
+			allowTypeMismatch: true,
+			want:              T{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			if tc.allowTypeMismatch {
+				dec.AllowTypeMismatch()
+			}
+			dec.SetVersionField("version", policies)
+
+			var got T
+			err := dec.Decode(&got)
+			if tc.wantErr {
+				var terr *UnmarshalTypeError
+				if !errors.As(err, &terr) {
+					t.Fatalf("%s: Decode error:\n\tgot:  %v\n\twant: UnmarshalTypeError", tc.Where, err)
+				}
+			} else if err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Fatalf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMismatchHandler(t *testing.T) {
+	type T struct {
+		Int     int     `json:"int"`
+		Float64 float64 `json:"float64"`
+	}
+
+	dec := NewDecoder(strings.NewReader(`{"int": 1.5, "float64": "MISMATCHED_TYPE"}`))
+	dec.AllowTypeMismatch()
+	var got []TypeMismatch
+	dec.SetMismatchHandler(func(m TypeMismatch) {
+		got = append(got, m)
+	})
+
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := []TypeMismatch{
+		{Value: "number 1.5", Raw: RawMessage(`1.5`), Type: reflect.TypeFor[int](), Offset: 11, Struct: "T", Field: "int", InputOffset: 8, ActualKind: KindNumber, Path: "int"},
+		{Value: "string", Raw: RawMessage(`"MISMATCHED_TYPE"`), Type: reflect.TypeFor[float64](), Offset: 41, Struct: "T", Field: "float64", InputOffset: 24, ActualKind: KindString, Path: "float64"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mismatch handler calls:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if m := dec.Mismatches(); m != nil {
+		t.Fatalf("Mismatches() without RecordMismatches = %v, want nil", m)
+	}
+}
+
+func TestAllowTypeMismatchAllocs(t *testing.T) {
+	type T struct {
+		Int     int     `json:"int"`
+		Uint    uint    `json:"uint"`
+		Float64 float64 `json:"float64"`
+		Bool    bool    `json:"bool"`
+		Slice   []int   `json:"slice"`
+	}
+
+	valid := []byte(`{"int": 1, "uint": 2, "float64": 3.5, "bool": true, "slice": null}`)
+	mismatched := []byte(`{"int": 1.5, "uint": -2, "float64": "3.5", "bool": 1, "slice": {}}`)
+
+	allocs := func(data []byte, allow bool) float64 {
+		r := bytes.NewReader(data)
+		dec := NewDecoder(r)
+		if allow {
+			dec.AllowTypeMismatch()
+		}
+		var v T
+		return testing.AllocsPerRun(100, func() {
+			r.Reset(data)
+			dec.Reset(r)
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("Decode error: %v", err)
+			}
+		})
+	}
+
+	strict := allocs(valid, false)
+	if got := allocs(valid, true); got != strict {
+		t.Errorf("allocs with AllowTypeMismatch and no mismatches = %v, want %v", got, strict)
+	}
+	if got := allocs(mismatched, true); got != strict {
+		t.Errorf("allocs with AllowTypeMismatch and unreported mismatches = %v, want %v", got, strict)
+	}
+}
+
+func TestAllowTypeMismatchSeededInterface(t *testing.T) {
+	type T struct {
+		Any any `json:"any"`
+	}
+
+	t.Run("Pointer", func(t *testing.T) {
+		n := 5
+		v := T{Any: &n}
+		dec := NewDecoder(strings.NewReader(`{"any": "MISMATCHED_TYPE"}`))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		p, ok := v.Any.(*int)
+		if !ok || p != &n {
+			t.Fatalf("Decode: Any = %#v, want the seeded *int", v.Any)
+		}
+		if n != 0 {
+			t.Fatalf("Decode: *Any = %d, want 0", n)
+		}
+		mismatches := dec.Mismatches()
+		if len(mismatches) != 1 || mismatches[0].Type != reflect.TypeFor[int]() || mismatches[0].Field != "any" {
+			t.Fatalf("Mismatches() = %+v, want a single mismatch of field any of type int", mismatches)
+		}
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		// A non-pointer value stored in an interface is not addressable,
+		// so it is replaced as encoding/json always does, there is no mismatch.
+		v := T{Any: 5}
+		dec := NewDecoder(strings.NewReader(`{"any": "test"}`))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if v.Any != "test" {
+			t.Fatalf("Decode: Any = %#v, want %q", v.Any, "test")
+		}
+		if m := dec.Mismatches(); m != nil {
+			t.Fatalf("Mismatches() = %v, want nil", m)
+		}
+	})
+}
+
+func TestAllowTypeMismatchZeroesTarget(t *testing.T) {
+	type T struct {
+		String string         `json:"string"`
+		Int    int            `json:"int"`
+		Slice  []int          `json:"slice"`
+		Map    map[string]int `json:"map"`
+	}
+
+	v := T{String: "old", Int: 1, Slice: []int{1}, Map: map[string]int{"a": 1}}
+	dec := NewDecoder(strings.NewReader(`{"string": 1, "int": 1.5, "slice": "x", "map": []}`))
+	dec.AllowTypeMismatch()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.String != "" || v.Int != 0 || v.Slice != nil || v.Map != nil {
+		t.Fatalf("Decode: got %+v, want zero value", v)
+	}
+}
+
+func TestAllowTypeMismatchSliceOfStructs(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	dec := NewDecoder(strings.NewReader(`[{"id": 1, "name": "a"}, {"id": "bad", "name": "b"}, {"id": 3, "name": 3}]`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+
+	var got []Item
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := []Item{{1, "a"}, {0, "b"}, {3, ""}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if n := len(dec.Mismatches()); n != 2 {
+		t.Fatalf("len(Mismatches()) = %d, want 2", n)
+	}
+}
+
+func TestSetMaxMismatches(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+		B int `json:"b"`
+		C int `json:"c"`
+		D int `json:"d"`
+	}
+
+	input := `{"a": "x", "b": "x", "c": "x", "d": 4}`
+
+	testCases := []struct {
+		CaseName
+
+		max       int
+		want      T
+		wantCount int // 0 means no error expected
+	}{
+		{CaseName: Name("Unlimited"), max: -1, want: T{D: 4}},
+		{CaseName: Name("BelowLimit"), max: 4, want: T{D: 4}},
+		{CaseName: Name("AtLimit"), max: 3, want: T{D: 4}},
+		{CaseName: Name("ExceedsLimit"), max: 1, want: T{}, wantCount: 2},
+		{CaseName: Name("NoMismatchesAllowed"), max: 0, want: T{}, wantCount: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(input))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetMaxMismatches(tc.max)
+
+			var got T
+			err := dec.Decode(&got)
+			if tc.wantCount == 0 {
+				if err != nil {
+					t.Fatalf("%s: Decode error: %v", tc.Where, err)
+				}
+			} else {
+				var merr *TooManyMismatchesError
+				if !errors.As(err, &merr) {
+					t.Fatalf("%s: Decode error:\n\tgot:  %v\n\twant: TooManyMismatchesError", tc.Where, err)
+				}
+				if merr.Count != tc.wantCount || merr.Limit != tc.max || len(merr.Mismatches) != tc.wantCount {
+					t.Fatalf("%s: TooManyMismatchesError = %+v, want Count and len(Mismatches) %d", tc.Where, merr, tc.wantCount)
+				}
+			}
+			if got != tc.want {
+				t.Fatalf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToleranceBudgetPerStruct(t *testing.T) {
+	type Item struct {
+		P int `json:"p"`
+		Q int `json:"q"`
+	}
+	type T struct {
+		A     int    `json:"a"`
+		Inner Item   `json:"inner"`
+		Items []Item `json:"items"`
+		Ints  []int  `json:"ints"`
+		B     int    `json:"b"`
+		C     int    `json:"c"`
+	}
+
+	input := `{"a": "x", "inner": {"p": "x", "q": "x"}, "items": [{"p": "x", "q": "x"}, {"p": "x"}], "ints": [1, "x"], "b": "x", "c": 3}`
+
+	testCases := []struct {
+		CaseName
+
+		budget    int
+		wantErr   string // field of the UnmarshalTypeError, if any
+		wantCount int    // tolerated mismatches
+	}{
+		{CaseName: Name("Unlimited"), budget: -1, wantCount: 8},
+		{CaseName: Name("WithinBudget"), budget: 3, wantCount: 8},
+		{CaseName: Name("ExceedsBudget"), budget: 2, wantErr: "b", wantCount: 7},
+		{CaseName: Name("OnePerStruct"), budget: 1, wantErr: "inner.q", wantCount: 4},
+		{CaseName: Name("NoMismatchesAllowed"), budget: 0, wantErr: "a", wantCount: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(input))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetToleranceBudgetPerStruct(tc.budget)
+
+			var got T
+			err := dec.Decode(&got)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("%s: Decode error: %v", tc.Where, err)
+				}
+			} else {
+				var terr *UnmarshalTypeError
+				if !errors.As(err, &terr) || terr.Field != tc.wantErr {
+					t.Fatalf("%s: Decode error:\n\tgot:  %v\n\twant: UnmarshalTypeError for %s", tc.Where, err, tc.wantErr)
+				}
+			}
+			if n := len(dec.Mismatches()); n != tc.wantCount {
+				t.Errorf("%s: %d mismatches, want %d", tc.Where, n, tc.wantCount)
+			}
+			// The rest of the value is decoded, as without tolerance.
+			if got.C != 3 {
+				t.Errorf("%s: Decode: C = %d, want 3", tc.Where, got.C)
+			}
+		})
+	}
+}
+
+// TestToleranceBudgetLastMismatch checks that the mismatch that reaches the
+// budget of a struct is repaired as the ones before it.
+func TestToleranceBudgetLastMismatch(t *testing.T) {
+	type Inner struct {
+		X int `json:"x"`
+	}
+	type T struct {
+		A int    `json:"a"`
+		P *Inner `json:"p"`
+		Q *Inner `json:"q"`
+	}
+
+	decode := func(in string, got *T, resolve bool) *Decoder {
+		t.Helper()
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		dec.SetToleranceBudgetPerStruct(1)
+		if resolve {
+			dec.SetMismatchResolver(func(path string, goType reflect.Type, raw RawMessage) (any, bool) {
+				return 42, goType.Kind() == reflect.Int
+			})
+		}
+		if err := dec.Decode(got); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if n := len(dec.Mismatches()); n != 1 {
+			t.Fatalf("%d mismatches, want 1", n)
+		}
+		return dec
+	}
+
+	got := T{A: 5}
+	decode(`{"a": "x"}`, &got, false)
+	if got.A != 0 {
+		t.Errorf("A = %d, want 0", got.A)
+	}
+
+	got = T{A: 5}
+	decode(`{"a": "x"}`, &got, true)
+	if got.A != 42 {
+		t.Errorf("A = %d, want 42 from the resolver", got.A)
+	}
+
+	got = T{}
+	decode(`{"p": 5}`, &got, false)
+	if got.P != nil {
+		t.Errorf("P = %+v, want nil", got.P)
+	}
+
+	got = T{}
+	decode(`{"q": [1]}`, &got, false)
+	if got.Q != nil {
+		t.Errorf("Q = %+v, want nil", got.Q)
+	}
+}
+
+func TestAllowTypeMismatchEmbedded(t *testing.T) {
+	type Base struct {
+		ID   int
+		Name string
+	}
+	type Value struct {
+		Base
+		Other int `json:"other"`
+	}
+	type Pointer struct {
+		*Base
+		Other int `json:"other"`
+	}
+
+	input := `{"id": "MISMATCHED_TYPE", "name": "test", "other": 1}`
+
+	t.Run("Value", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		v := Value{Base: Base{ID: 5}}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if want := (Value{Base{Name: "test"}, 1}); v != want {
+			t.Fatalf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+		}
+		mismatches := dec.Mismatches()
+		if len(mismatches) != 1 || mismatches[0].Field != "ID" || mismatches[0].Struct != "Value" {
+			t.Fatalf("Mismatches() = %+v, want a single mismatch of field ID of Value", mismatches)
+		}
+	})
+
+	t.Run("Pointer", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		var v Pointer
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if v.Base == nil {
+			t.Fatalf("Decode: embedded pointer is nil, want it allocated")
+		}
+		if want := (Base{Name: "test"}); *v.Base != want || v.Other != 1 {
+			t.Fatalf("Decode:\n\tgot:  %+v, %d\n\twant: %+v, 1", *v.Base, v.Other, want)
+		}
+		mismatches := dec.Mismatches()
+		if len(mismatches) != 1 || mismatches[0].Field != "ID" || mismatches[0].Struct != "Pointer" {
+			t.Fatalf("Mismatches() = %+v, want a single mismatch of field ID of Pointer", mismatches)
+		}
+	})
+}
+
+func TestAllowTypeMismatchStructuredIntoScalar(t *testing.T) {
+	type T struct {
+		Int    int     `json:"int"`
+		String string  `json:"string"`
+		Bool   bool    `json:"bool"`
+		Float  float64 `json:"float"`
+	}
+
+	testCases := []struct {
+		CaseName
+
+		input          string
+		want           T
+		wantMismatches int
+	}{
+		{
+			CaseName:       Name("ObjectIntoInt"),
+			input:          `{"int": {"nested": 1}, "string": "test", "bool": true, "float": 1.5}`,
+			want:           T{String: "test", Bool: true, Float: 1.5},
+			wantMismatches: 1,
+		},
+		{
+			CaseName:       Name("ArrayIntoString"),
+			input:          `{"int": 1, "string": ["a", {"b": "}"}], "bool": true, "float": 1.5}`,
+			want:           T{Int: 1, Bool: true, Float: 1.5},
+			wantMismatches: 1,
+		},
+		{
+			CaseName:       Name("ObjectIntoBool"),
+			input:          `{"int": 1, "string": "test", "bool": {"a": [1, 2, {"b": "]"}]}, "float": 1.5}`,
+			want:           T{Int: 1, String: "test", Float: 1.5},
+			wantMismatches: 1,
+		},
+		{
+			CaseName:       Name("EmptyContainersIntoFloat"),
+			input:          `{"float": {}, "int": 1, "string": [], "bool": true}`,
+			want:           T{Int: 1, Bool: true},
+			wantMismatches: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Fatalf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+			if n := len(dec.Mismatches()); n != tc.wantMismatches {
+				t.Fatalf("%s: len(Mismatches()) = %d, want %d", tc.Where, n, tc.wantMismatches)
+			}
+		})
+	}
+}
+
+func TestAllowTypeMismatchSkipsNestedValues(t *testing.T) {
+	type T struct {
+		Int   int      `json:"int"`
+		Slice []int    `json:"slice"`
+		After string   `json:"after"`
+		Tail  []string `json:"tail"`
+	}
+
+	const depth = 1000
+	nested := strings.Repeat(`[{"a": "]}", "b": [`, depth) + `1` + strings.Repeat(`]}]`, depth)
+
+	testCases := []struct {
+		CaseName
+
+		input string
+		want  T
+	}{
+		{
+			CaseName: Name("NestedArrayIntoScalar"),
+			input:    `{"int": ` + nested + `, "after": "test", "tail": ["a"]}`,
+			want:     T{After: "test", Tail: []string{"a"}},
+		},
+		{
+			CaseName: Name("NestedArrayElements"),
+			input:    `{"slice": [1, ` + nested + `, 3], "after": "test", "tail": ["a"]}`,
+			want:     T{Slice: []int{1, 0, 3}, After: "test", Tail: []string{"a"}},
+		},
+		{
+			CaseName: Name("NestedObjectIntoSlice"),
+			input:    `{"slice": {"a": ` + nested + `}, "int": 1, "after": "test", "tail": ["a"]}`,
+			want:     T{Int: 1, After: "test", Tail: []string{"a"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// A second value follows the first one, to check that the decoder
+			// is still in sync after skipping the mismatched value.
+			dec := NewDecoder(strings.NewReader(tc.input + ` {"int": 2}`))
+			dec.AllowTypeMismatch()
+
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got.Int != tc.want.Int || got.After != tc.want.After ||
+				!slices.Equal(got.Slice, tc.want.Slice) || !slices.Equal(got.Tail, tc.want.Tail) {
+				t.Fatalf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+
+			var next T
+			if err := dec.Decode(&next); err != nil {
+				t.Fatalf("%s: second Decode error: %v", tc.Where, err)
+			}
+			if next.Int != 2 {
+				t.Fatalf("%s: second Decode: Int = %d, want 2", tc.Where, next.Int)
+			}
+		})
+	}
+}
+
+func TestDecodeValid(t *testing.T) {
+	type T struct {
+		String string `json:"string"`
+		Int    int    `json:"int"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		got, mismatches, err := DecodeValid[T]([]byte(`{"string": "test", "int": 123}`))
+		if err != nil {
+			t.Fatalf("DecodeValid error: %v", err)
+		}
+		if want := (T{"test", 123}); got != want {
+			t.Fatalf("DecodeValid:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		if mismatches != nil {
+			t.Fatalf("DecodeValid mismatches = %v, want nil", mismatches)
+		}
+	})
+
+	t.Run("Mismatched", func(t *testing.T) {
+		got, mismatches, err := DecodeValid[*T]([]byte(`{"string": 123, "int": 123}`))
+		if err != nil {
+			t.Fatalf("DecodeValid error: %v", err)
+		}
+		if want := (T{Int: 123}); got == nil || *got != want {
+			t.Fatalf("DecodeValid:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		want := []TypeMismatch{{Value: "number", Raw: RawMessage(`123`), Type: reflect.TypeFor[string](), Offset: 14, Struct: "T", Field: "string", InputOffset: 11, ActualKind: KindNumber, Path: "string"}}
+		if !reflect.DeepEqual(mismatches, want) {
+			t.Fatalf("DecodeValid mismatches:\n\tgot:  %+v\n\twant: %+v", mismatches, want)
+		}
+	})
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		_, _, err := DecodeValid[T]([]byte(`{"string": "test"`))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("DecodeValid error:\n\tgot:  %v\n\twant: SyntaxError", err)
+		}
+	})
+}
+
+func TestAllowTypeMismatchStream(t *testing.T) {
+	type T struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	tests := []struct {
+		CaseName
+		in string
+		// array reports whether in is a single JSON array walked with Token
+		// and More instead of a sequence of top-level values.
+		array bool
+	}{{
+		CaseName: Name("Sequence"),
+		in: `{"id": 1, "name": "a", "count": 10}
+{"id": "2", "name": "b", "count": 20}
+{"id": 3, "name": 3, "count": true}
+{"id": 4, "name": "d", "count": 40}`,
+	}, {
+		CaseName: Name("Array"),
+		in: `[{"id": 1, "name": "a", "count": 10},
+{"id": "2", "name": "b", "count": 20},
+{"id": 3, "name": 3, "count": true},
+{"id": 4, "name": "d", "count": 40}]`,
+		array: true,
+	}}
+	want := []T{
+		{ID: 1, Name: "a", Count: 10},
+		{Name: "b", Count: 20},
+		{ID: 3},
+		{ID: 4, Name: "d", Count: 40},
+	}
+	wantFields := [][]string{
+		nil,
+		{"id"},
+		{"name", "count"},
+		nil,
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			if tc.array {
+				if tok, err := dec.Token(); err != nil || tok != Delim('[') {
+					t.Fatalf("%s: Token = %v, %v, want [", tc.Where, tok, err)
+				}
+			}
+			var got []T
+			for i := 0; dec.More(); i++ {
+				var v T
+				if err := dec.Decode(&v); err != nil {
+					t.Fatalf("%s: Decode #%d error: %v", tc.Where, i, err)
+				}
+				got = append(got, v)
+				var fields []string
+				for _, m := range dec.Mismatches() {
+					fields = append(fields, m.Field)
+				}
+				if i < len(wantFields) && !slices.Equal(fields, wantFields[i]) {
+					t.Errorf("%s: Decode #%d mismatched fields = %q, want %q", tc.Where, i, fields, wantFields[i])
+				}
+			}
+			if tc.array {
+				if tok, err := dec.Token(); err != nil || tok != Delim(']') {
+					t.Fatalf("%s: Token = %v, %v, want ]", tc.Where, tok, err)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: decoded values:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, want)
+			}
+		})
+	}
+}
+
+func TestAllowTypeMismatchConcatenated(t *testing.T) {
+	type T struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	// Values separated by newlines, by spaces, or by nothing, with the
+	// mismatches at their start, in their middle and at their end, and a
+	// mismatched value skipped as a whole.
+	in := `{"id": "1", "tags": ["a"]}
+{"id": 2, "tags": [{"x": [1, {}]}, "b"]}{"id": 3, "tags": 7} {"id": 4}
+[1, 2]
+"5"{"id": 6, "tags": ["c", true]}`
+	type record struct {
+		want      T
+		paths     []string
+		positions []int64 // InputOffset of the mismatches
+	}
+	records := []record{
+		{T{Tags: []string{"a"}}, []string{"id"}, []int64{7}},
+		{T{ID: 2, Tags: []string{"", "b"}}, []string{"tags[0]"}, []int64{46}},
+		{T{ID: 3}, []string{"tags"}, []int64{85}},
+		{T{ID: 4}, nil, nil},
+		{T{}, []string{""}, []int64{98}},
+		{T{}, []string{""}, []int64{105}},
+		{T{ID: 6, Tags: []string{"c", ""}}, []string{"tags[1]"}, []int64{132}},
+	}
+	readers := []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{"Reader", func() io.Reader { return strings.NewReader(in) }},
+		{"OneByteReader", func() io.Reader { return iotest.OneByteReader(strings.NewReader(in)) }},
+		{"HalfReader", func() io.Reader { return iotest.HalfReader(strings.NewReader(in)) }},
+	}
+	for _, tc := range readers {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(tc.r())
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			for i, rec := range records {
+				var got T
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("record %d: Decode error: %v", i, err)
+				}
+				if !reflect.DeepEqual(got, rec.want) {
+					t.Errorf("record %d: Decode:\n\tgot:  %+v\n\twant: %+v", i, got, rec.want)
+				}
+				var paths []string
+				var positions []int64
+				for _, m := range dec.Mismatches() {
+					paths = append(paths, m.Path)
+					positions = append(positions, m.InputOffset)
+				}
+				if !slices.Equal(paths, rec.paths) || !slices.Equal(positions, rec.positions) {
+					t.Errorf("record %d: mismatches at %q %v, want %q %v", i, paths, positions, rec.paths, rec.positions)
+				}
+			}
+			if err := dec.Decode(new(T)); err != io.EOF {
+				t.Errorf("Decode after the last record: %v, want io.EOF", err)
+			}
+			if dec.HadMismatch() {
+				t.Errorf("HadMismatch after io.EOF")
+			}
+		})
+	}
+}
+
+func TestSetStringCoercion(t *testing.T) {
+	type T struct {
+		Int     int     `json:"int"`
+		Uint8   uint8   `json:"uint8"`
+		Float64 float64 `json:"float64"`
+		Bool    bool    `json:"bool"`
+	}
+	tests := []struct {
+		CaseName
+		in     string
+		want   T
+		fields []string
+	}{{
+		CaseName: Name("Coerced"),
+		in:       `{"int": "123", "uint8": "7", "float64": "12.5", "bool": "true"}`,
+		want:     T{Int: 123, Uint8: 7, Float64: 12.5, Bool: true},
+	}, {
+		CaseName: Name("Uncoercible"),
+		in:       `{"int": "abc", "uint8": "abc", "float64": "abc", "bool": "abc"}`,
+		want:     T{},
+		fields:   []string{"int", "uint8", "float64", "bool"},
+	}, {
+		CaseName: Name("WrongNumberKind"),
+		in:       `{"int": "12.5", "uint8": "-1", "float64": "NaN", "bool": "1"}`,
+		want:     T{Bool: true},
+		fields:   []string{"int", "uint8", "float64"},
+	}, {
+		CaseName: Name("Overflow"),
+		in:       `{"int": "1", "uint8": "256", "float64": "1e400"}`,
+		want:     T{Int: 1},
+		fields:   []string{"uint8", "float64"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetStringCoercion(true)
+			got := T{Int: -1, Uint8: 1, Float64: -1}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+			var fields []string
+			for _, m := range dec.Mismatches() {
+				fields = append(fields, m.Field)
+			}
+			if !slices.Equal(fields, tc.fields) {
+				t.Errorf("%s: mismatched fields = %q, want %q", tc.Where, fields, tc.fields)
+			}
+		})
+	}
+
+	// Without AllowTypeMismatch, uncoercible strings are still errors.
+	dec := NewDecoder(strings.NewReader(`{"int": "123", "bool": "abc"}`))
+	dec.SetStringCoercion(true)
+	var v T
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(&v); !errors.As(err, &ute) || ute.Field != "bool" {
+		t.Fatalf("Decode error: %v, want UnmarshalTypeError for field bool", err)
+	}
+	if v.Int != 123 {
+		t.Errorf("Decode: Int = %d, want 123", v.Int)
+	}
+}
+
+func TestSetDecimalComma(t *testing.T) {
+	type T struct {
+		Int     int     `json:"int"`
+		Uint16  uint16  `json:"uint16"`
+		Float64 float64 `json:"float64"`
+		Float32 float32 `json:"float32"`
+	}
+	tests := []struct {
+		CaseName
+		in     string
+		want   T
+		fields []string
+	}{{
+		CaseName: Name("Plain"),
+		in:       `{"int": "-12", "uint16": "7", "float64": "0,5", "float32": "-3,25"}`,
+		want:     T{Int: -12, Uint16: 7, Float64: 0.5, Float32: -3.25},
+	}, {
+		CaseName: Name("Thousands"),
+		in:       `{"int": "-1.234.567", "uint16": "65.535", "float64": "1.234,56", "float32": "12.345"}`,
+		want:     T{Int: -1234567, Uint16: 65535, Float64: 1234.56, Float32: 12345},
+	}, {
+		CaseName: Name("DecimalPoint"),
+		in:       `{"int": "1,5", "uint16": "1.5", "float64": "1.5", "float32": "1e3"}`,
+		fields:   []string{"int", "uint16", "float64", "float32"},
+	}, {
+		CaseName: Name("BadGroups"),
+		in:       `{"int": "1.23", "uint16": "1234.567", "float64": ".123,4", "float32": "1.234,"}`,
+		fields:   []string{"int", "uint16", "float64", "float32"},
+	}, {
+		CaseName: Name("Overflow"),
+		in:       `{"int": "1", "uint16": "65.536", "float64": "2,5"}`,
+		want:     T{Int: 1, Float64: 2.5},
+		fields:   []string{"uint16"},
+	}, {
+		CaseName: Name("Numbers"),
+		in:       `{"int": 1234, "float64": 1.5}`,
+		want:     T{Int: 1234, Float64: 1.5},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetStringCoercion(true)
+			dec.SetDecimalComma(true)
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+			var fields []string
+			for _, m := range dec.Mismatches() {
+				fields = append(fields, m.Field)
+			}
+			if !slices.Equal(fields, tc.fields) {
+				t.Errorf("%s: mismatched fields = %q, want %q", tc.Where, fields, tc.fields)
+			}
+		})
+	}
+}
+
+func TestAllNumbersAsStrings(t *testing.T) {
+	type Row struct {
+		ID    int64   `json:"id"`
+		Count uint8   `json:"count"`
+		Score float64 `json:"score"`
+		Ok    bool    `json:"ok"`
+		Name  string  `json:"name"`
+	}
+	type T struct {
+		Rows   []Row      `json:"rows"`
+		Totals [2]float32 `json:"totals"`
+		Max    *int       `json:"max"`
+		Plain  int        `json:"plain"`
+	}
+	in := `{
+		"rows": [
+			{"id": "9007199254740993", "count": "7", "score": "1.5e2", "ok": "true", "name": "a"},
+			{"id": "-1", "count": "300", "score": "NaN", "ok": true, "name": "b"},
+			{"id": "1.5", "count": "x", "score": "-Infinity", "ok": false, "name": "12"}
+		],
+		"totals": ["Infinity", " 2"],
+		"max": "42",
+		"plain": 3
+	}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetAllNumbersAsStrings(true)
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if len(got.Rows) != 3 {
+		t.Fatalf("Decode: len(Rows) = %d, want 3", len(got.Rows))
+	}
+	if !math.IsNaN(got.Rows[1].Score) {
+		t.Errorf("Decode: Rows[1].Score = %v, want NaN", got.Rows[1].Score)
+	}
+	got.Rows[1].Score = 0
+	want := T{
+		Rows: []Row{
+			{ID: 9007199254740993, Count: 7, Score: 150, Name: "a"},
+			{ID: -1, Ok: true, Name: "b"},
+			{Score: math.Inf(-1), Name: "12"},
+		},
+		Totals: [2]float32{float32(math.Inf(1)), 0},
+		Max:    new(int),
+		Plain:  3,
+	}
+	*want.Max = 42
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	wantPaths := []string{"rows[0].ok", "rows[1].count", "rows[2].id", "rows[2].count", "totals[1]"}
+	if !slices.Equal(paths, wantPaths) {
+		t.Errorf("mismatched paths = %q, want %q", paths, wantPaths)
+	}
+
+	// Off by default: quoted numbers are mismatches.
+	dec = NewDecoder(strings.NewReader(`{"plain": "3"}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	got = T{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got.Plain != 0 || len(dec.Mismatches()) != 1 {
+		t.Errorf("Decode: Plain = %d with %d mismatches, want 0 with 1", got.Plain, len(dec.Mismatches()))
+	}
+}
+
+func TestSetDurationStrings(t *testing.T) {
+	type T struct {
+		Timeout time.Duration `json:"timeout"`
+	}
+	tests := []struct {
+		CaseName
+		in              string
+		durationStrings bool
+		want            time.Duration
+		mismatch        bool
+	}{
+		{CaseName: Name(""), in: `{"timeout": 300000000000}`, want: 5 * time.Minute},
+		{CaseName: Name(""), in: `{"timeout": 300000000000}`, durationStrings: true, want: 5 * time.Minute},
+		{CaseName: Name(""), in: `{"timeout": "5m"}`, mismatch: true},
+		{CaseName: Name(""), in: `{"timeout": "5m"}`, durationStrings: true, want: 5 * time.Minute},
+		{CaseName: Name(""), in: `{"timeout": "1h30m"}`, durationStrings: true, want: 90 * time.Minute},
+		{CaseName: Name(""), in: `{"timeout": "-1.5s"}`, durationStrings: true, want: -1500 * time.Millisecond},
+		{CaseName: Name(""), in: `{"timeout": "5 minutes"}`, durationStrings: true, mismatch: true},
+		{CaseName: Name(""), in: `{"timeout": "300"}`, durationStrings: true, mismatch: true},
+		{CaseName: Name(""), in: `{"timeout": 1.5}`, durationStrings: true, mismatch: true},
+		{CaseName: Name(""), in: `{"timeout": true}`, durationStrings: true, mismatch: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetDurationStrings(tc.durationStrings)
+			got := T{Timeout: time.Second}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got.Timeout != tc.want {
+				t.Errorf("%s: Decode: Timeout = %v, want %v", tc.Where, got.Timeout, tc.want)
+			}
+			if m := dec.Mismatches(); (len(m) != 0) != tc.mismatch {
+				t.Errorf("%s: Mismatches = %v, want mismatch: %v", tc.Where, m, tc.mismatch)
+			}
+		})
+	}
+}
+
+func TestRegisterCoercion(t *testing.T) {
+	type Point struct {
+		X, Y int
+	}
+	type T struct {
+		Point  Point           `json:"point"`
+		PtrPt  *Point          `json:"ptr"`
+		Points []Point         `json:"points"`
+		Map    map[string]bool `json:"map"`
+	}
+	// parsePoint parses points written as "X,Y" strings or [X, Y] arrays.
+	parsePoint := func(raw RawMessage) (any, error) {
+		var s string
+		if err := Unmarshal(raw, &s); err == nil {
+			var p Point
+			_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+			return p, err
+		}
+		var a [2]int
+		if err := Unmarshal(raw, &a); err != nil {
+			return nil, err
+		}
+		return Point{a[0], a[1]}, nil
+	}
+	parseMap := func(raw RawMessage) (any, error) {
+		// Returning a value of the wrong type is a mismatch.
+		return "not a map", nil
+	}
+
+	in := `{"point": "1,2", "ptr": [3, 4], "points": [{"X": 5, "Y": 6}, "7,8", "bad", true], "map": []}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.RegisterCoercion(reflect.TypeFor[Point](), parsePoint)
+	dec.RegisterCoercion(reflect.TypeFor[map[string]bool](), parseMap)
+	got := T{Map: map[string]bool{"a": true}}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{
+		Point:  Point{1, 2},
+		PtrPt:  &Point{3, 4},
+		Points: []Point{{5, 6}, {7, 8}, {}, {}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var values []string
+	for _, m := range dec.Mismatches() {
+		values = append(values, m.Value)
+	}
+	if want := []string{"string", "bool", "array"}; !slices.Equal(values, want) {
+		t.Errorf("mismatched values = %q, want %q", values, want)
+	}
+
+	// Coercions also apply without AllowTypeMismatch.
+	dec = NewDecoder(strings.NewReader(`{"point": "1,2"}`))
+	dec.RegisterCoercion(reflect.TypeFor[Point](), parsePoint)
+	got = T{}
+	if err := dec.Decode(&got); err != nil || got.Point != (Point{1, 2}) {
+		t.Errorf("Decode = %+v, %v, want point {1 2} and no error", got.Point, err)
+	}
+
+	dec = NewDecoder(strings.NewReader(`{"point": "1,2"}`))
+	dec.RegisterCoercion(reflect.TypeFor[Point](), parsePoint)
+	dec.RegisterCoercion(reflect.TypeFor[Point](), nil)
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(&got); !errors.As(err, &ute) {
+		t.Errorf("Decode after removing coercion: error %v, want UnmarshalTypeError", err)
+	}
+}
+
+// warnLogger is a [Logger] that records the formatted warnings.
+type warnLogger []string
+
+func (l *warnLogger) Warnf(format string, args ...any) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	type Inner struct {
+		Bool bool `json:"bool"`
+	}
+	type T struct {
+		Int   int   `json:"int"`
+		Inner Inner `json:"inner"`
+	}
+
+	var l warnLogger
+	dec := NewDecoder(strings.NewReader(`{"int": "1", "inner": {"bool": 0}} [true]`))
+	dec.AllowTypeMismatch()
+	dec.SetLogger(&l)
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	var x int
+	if err := dec.Decode(&x); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := warnLogger{
+		"json: type mismatch at offset 11: cannot unmarshal string into Go struct field T.int of type int",
+		"json: type mismatch at offset 32: cannot unmarshal number into Go struct field Inner.inner.bool of type bool",
+		"json: type mismatch at offset 2: cannot unmarshal array into Go value of type int",
+	}
+	if !slices.Equal(l, want) {
+		t.Errorf("logged warnings:\n\tgot:  %q\n\twant: %q", l, want)
+	}
+	if m := dec.Mismatches(); m != nil {
+		t.Errorf("Mismatches() with only a logger = %v, want nil", m)
+	}
+
+	l = nil
+	dec = NewDecoder(strings.NewReader(`{"int": "1"}`))
+	dec.AllowTypeMismatch()
+	dec.SetLogger(&l)
+	dec.SetLogger(nil)
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if l != nil {
+		t.Errorf("logged warnings after SetLogger(nil): %q", l)
+	}
+}
+
+func TestAllowTypeMismatchSQLNullTypes(t *testing.T) {
+	type T struct {
+		String  sql.NullString      `json:"string"`
+		Int64   sql.NullInt64       `json:"int64"`
+		Float64 sql.NullFloat64     `json:"float64"`
+		Bool    sql.NullBool        `json:"bool"`
+		Time    sql.Null[time.Time] `json:"time"`
+	}
+	valid := T{
+		String:  sql.NullString{String: "a", Valid: true},
+		Int64:   sql.NullInt64{Int64: 1, Valid: true},
+		Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+		Bool:    sql.NullBool{Bool: true, Valid: true},
+		Time:    sql.Null[time.Time]{V: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+	}
+	tests := []struct {
+		CaseName
+		in   string
+		want T
+	}{{
+		CaseName: Name("Valid"),
+		in: `{"string": {"String": "a", "Valid": true}, "int64": {"Int64": 1, "Valid": true},
+			"float64": {"Float64": 1.5, "Valid": true}, "bool": {"Bool": true, "Valid": true},
+			"time": {"V": "2024-01-02T03:04:05Z", "Valid": true}}`,
+		want: valid,
+	}, {
+		CaseName: Name("Scalars"),
+		in:       `{"string": "a", "int64": 1, "float64": 1.5, "bool": true, "time": "2024-01-02T03:04:05Z"}`,
+	}, {
+		CaseName: Name("Arrays"),
+		in:       `{"string": ["a"], "int64": [1], "float64": [1.5], "bool": [true], "time": []}`,
+	}, {
+		CaseName: Name("Null"),
+		in:       `{"string": null, "int64": null, "float64": null, "bool": null, "time": null}`,
+		want:     valid,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			got := valid
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetOverflowMismatch(t *testing.T) {
+	type T struct {
+		Float32 float32 `json:"float32"`
+		Float64 float64 `json:"float64"`
+		Int8    int8    `json:"int8"`
+		Uint8   uint8   `json:"uint8"`
+	}
+	tests := []struct {
+		CaseName
+		in     string
+		want   T
+		fields []string
+	}{{
+		CaseName: Name("InRange"),
+		in:       `{"float32": 3.4e38, "float64": 1e40, "int8": -128, "uint8": 255}`,
+		want:     T{Float32: 3.4e38, Float64: 1e40, Int8: -128, Uint8: 255},
+	}, {
+		CaseName: Name("Float32"),
+		in:       `{"float32": 1e40, "float64": 1e40}`,
+		want:     T{Float64: 1e40},
+		fields:   []string{"float32"},
+	}, {
+		CaseName: Name("NegativeFloat32"),
+		in:       `{"float32": -1e40}`,
+		fields:   []string{"float32"},
+	}, {
+		CaseName: Name("Float64"),
+		in:       `{"float32": 1.5, "float64": 1e400}`,
+		want:     T{Float32: 1.5},
+		fields:   []string{"float64"},
+	}, {
+		CaseName: Name("Integers"),
+		in:       `{"int8": 128, "uint8": 256}`,
+		fields:   []string{"int8", "uint8"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetOverflowMismatch(true)
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+			var fields []string
+			for _, m := range dec.Mismatches() {
+				fields = append(fields, m.Field)
+			}
+			if !slices.Equal(fields, tc.fields) {
+				t.Errorf("%s: mismatched fields = %q, want %q", tc.Where, fields, tc.fields)
+			}
+
+			// Overflows are errors unless SetOverflowMismatch is on.
+			dec = NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			var ute *UnmarshalTypeError
+			if err := dec.Decode(&got); (err != nil) != (tc.fields != nil) || err != nil && !errors.As(err, &ute) {
+				t.Errorf("%s: Decode without SetOverflowMismatch: error %v, want UnmarshalTypeError: %v", tc.Where, err, tc.fields != nil)
+			}
+		})
+	}
+}
+
+func TestSetMergeMode(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+		Zip  int    `json:"zip"`
+	}
+	type T struct {
+		Name    string         `json:"name"`
+		Age     int            `json:"age"`
+		Email   string         `json:"email"`
+		Tags    []string       `json:"tags"`
+		Address Address        `json:"address"`
+		Extra   map[string]int `json:"extra"`
+	}
+	current := func() T {
+		return T{
+			Name:    "gopher",
+			Age:     10,
+			Email:   "gopher@example.com",
+			Tags:    []string{"a", "b"},
+			Address: Address{City: "Paris", Zip: 75000},
+			Extra:   map[string]int{"x": 1, "y": 2},
+		}
+	}
+	in := `{"age": "eleven", "email": "new@example.com", "tags": ["c", 4],
+		"address": {"zip": "none"}, "extra": {"y": "two", "z": 3}}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetMergeMode(true)
+	got := current()
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{
+		Name:    "gopher",
+		Age:     10,
+		Email:   "new@example.com",
+		Tags:    []string{"c", "b"},
+		Address: Address{City: "Paris", Zip: 75000},
+		Extra:   map[string]int{"x": 1, "y": 2, "z": 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode in merge mode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if n := len(dec.Mismatches()); n != 4 {
+		t.Errorf("Decode in merge mode: got %d mismatches, want 4", n)
+	}
+
+	// Without merge mode the mismatched values are zeroed.
+	dec = NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	got = current()
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want.Age = 0
+	want.Tags = []string{"c", ""}
+	want.Address.Zip = 0
+	want.Extra["y"] = 0
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode without merge mode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+}
+
+func TestTypeMismatchRaw(t *testing.T) {
+	type T struct {
+		Int    int            `json:"int"`
+		Bool   bool           `json:"bool"`
+		String string         `json:"string"`
+		Keys   map[int]string `json:"keys"`
+	}
+	in := []byte(`{"int": {"a": [1, 2]}, "bool": "true", "string": [ "x" ], "keys": {"1.5": "a"}}`)
+	var v T
+	dec := NewDecoder(bytes.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	var raws []string
+	for _, m := range dec.Mismatches() {
+		raws = append(raws, string(m.Raw))
+	}
+	want := []string{`{"a": [1, 2]}`, `"true"`, `[ "x" ]`, `"1.5"`}
+	if !slices.Equal(raws, want) {
+		t.Fatalf("mismatched raw values:\n\tgot:  %q\n\twant: %q", raws, want)
+	}
+
+}
+
+func TestEncoderOmitPaths(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type T struct {
+		Base
+		Title string          `json:"title"`
+		Price float64         `json:"price"`
+		Items []Item          `json:"items"`
+		Inner *Item           `json:"inner"`
+		Tags  map[string]int  `json:"tags"`
+		ByKey map[string]Item `json:"by_key"`
+	}
+	in := `{"id": "1", "title": "t", "price": "free", "items": [{"name": "a", "count": "x"}, {"name": "b", "count": 2}],
+		"inner": {"name": 5, "count": 3}, "tags": {"a": 1, "b": "2"}, "by_key": {"c": {"name": "c", "count": "y"}}}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	var buf strings.Builder
+	enc := NewEncoder(&buf)
+	enc.OmitPaths(dec.Mismatches())
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want := `{"title":"t","items":[{"name":"a"},{"name":"b"}],"inner":{"count":3},"by_key":{"c":{"name":"c"}}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode with OmitPaths:\n\tgot:  %s\twant: %s", got, want)
+	}
+
+	buf.Reset()
+	enc.OmitPaths(nil)
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want = `{"id":0,"title":"t","price":0,"items":[{"name":"a","count":0},{"name":"b","count":2}],"inner":{"name":"","count":3},"tags":{"a":1,"b":0},"by_key":{"c":{"name":"c","count":0}}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode after OmitPaths(nil):\n\tgot:  %s\twant: %s", got, want)
+	}
+}
+
+func TestEncoderNullPaths(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,omitempty"`
+	}
+	type T struct {
+		Title string         `json:"title"`
+		Price float64        `json:"price"`
+		Items []Item         `json:"items"`
+		Tags  map[string]int `json:"tags"`
+		Note  string         `json:"note,omitempty"`
+	}
+	in := `{"title": "t", "price": "free", "items": [{"name": "a", "count": "x"}, {"name": "b", "count": 2}],
+		"tags": {"a": "1"}, "note": 1}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	var buf strings.Builder
+	enc := NewEncoder(&buf)
+	enc.NullPaths(dec.Mismatches())
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want := `{"title":"t","price":null,"items":[{"name":"a","count":null},{"name":"b","count":null}],"tags":null,"note":null}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode with NullPaths:\n\tgot:  %s\twant: %s", got, want)
+	}
+
+	// OmitPaths takes precedence.
+	buf.Reset()
+	enc.OmitPaths([]TypeMismatch{{Field: "price"}})
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want = `{"title":"t","items":[{"name":"a","count":null},{"name":"b","count":null}],"tags":null,"note":null}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode with OmitPaths and NullPaths:\n\tgot:  %s\twant: %s", got, want)
+	}
+
+	buf.Reset()
+	enc.OmitPaths(nil)
+	enc.NullPaths(nil)
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want = `{"title":"t","price":0,"items":[{"name":"a"},{"name":"b","count":2}],"tags":{"a":0}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode after NullPaths(nil):\n\tgot:  %s\twant: %s", got, want)
+	}
+}
+
+func TestAllowTypeMismatchBoolIntoScalar(t *testing.T) {
+	type T struct {
+		String  string  `json:"string"`
+		Int     int     `json:"int"`
+		Uint    uint    `json:"uint"`
+		Float64 float64 `json:"float64"`
+		After   string  `json:"after"`
+	}
+	tests := []struct {
+		CaseName
+		in     string
+		fields []string
+	}{{
+		CaseName: Name("True"),
+		in:       `{"string": true, "int": true, "uint": true, "float64": true, "after": "ok"}`,
+		fields:   []string{"string", "int", "uint", "float64"},
+	}, {
+		CaseName: Name("False"),
+		in:       `{"string":false,"int":false,"uint":false,"float64":false,"after":"ok"}`,
+		fields:   []string{"string", "int", "uint", "float64"},
+	}, {
+		CaseName: Name("InArray"),
+		in:       `{"string": "a", "int": 1, "uint": 2, "float64": 3, "after": "ok"} [true, false]`,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			got := T{String: "seed", Int: 1, Uint: 1, Float64: 1}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			want := T{After: "ok"}
+			if tc.fields == nil {
+				want = T{String: "a", Int: 1, Uint: 2, Float64: 3, After: "ok"}
+			}
+			if got != want {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, want)
+			}
+			var fields []string
+			for _, m := range dec.Mismatches() {
+				if m.Value != "bool" {
+					t.Errorf("%s: mismatch Value = %q, want %q", tc.Where, m.Value, "bool")
+				}
+				fields = append(fields, m.Field)
+			}
+			if !slices.Equal(fields, tc.fields) {
+				t.Errorf("%s: mismatched fields = %q, want %q", tc.Where, fields, tc.fields)
+			}
+			if tc.fields != nil {
+				return
+			}
+
+			// Booleans are consumed entirely, even as elements of an array.
+			var s []string
+			if err := dec.Decode(&s); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if want := []string{"", ""}; !slices.Equal(s, want) {
+				t.Errorf("%s: Decode = %q, want %q", tc.Where, s, want)
+			}
+			if n := len(dec.Mismatches()); n != 2 {
+				t.Errorf("%s: got %d mismatches, want 2", tc.Where, n)
+			}
+			if dec.More() {
+				t.Errorf("%s: More = true after the last value", tc.Where)
+			}
+		})
+	}
+}
+
+func TestDisallowTypeMismatch(t *testing.T) {
+	type T struct {
+		Int int `json:"int"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"int": "1"} {"int": "2"} {"int": "3"} {"int": "4"} {"int": 5}`))
+	dec.RecordMismatches()
+	// allows reports whether AllowTypeMismatch is in effect for each value.
+	allows := []bool{false, true, false, true, false}
+	for i, allow := range allows {
+		if allow {
+			dec.AllowTypeMismatch()
+		} else {
+			dec.DisallowTypeMismatch()
+		}
+		v := T{Int: -1}
+		err := dec.Decode(&v)
+		switch {
+		case i == len(allows)-1:
+			if err != nil || v.Int != 5 {
+				t.Errorf("Decode #%d = %+v, %v, want {Int:5} and no error", i, v, err)
+			}
+		case allow:
+			if err != nil || v.Int != 0 || len(dec.Mismatches()) != 1 {
+				t.Errorf("Decode #%d = %+v, %v, %v mismatches, want {Int:0}, no error and 1 mismatch", i, v, err, len(dec.Mismatches()))
+			}
+		default:
+			var ute *UnmarshalTypeError
+			if !errors.As(err, &ute) || dec.Mismatches() != nil {
+				t.Errorf("Decode #%d error = %v, %v mismatches, want UnmarshalTypeError and no mismatches", i, err, len(dec.Mismatches()))
+			}
+		}
+	}
+}
+
+func TestHadMismatch(t *testing.T) {
+	type T struct {
+		Int int `json:"int"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"int": 1} {"int": "2"} {"int": 3} {"int": [4]}`))
+	dec.AllowTypeMismatch()
+	for i, want := range []bool{false, true, false, true} {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode #%d error: %v", i, err)
+		}
+		if got := dec.HadMismatch(); got != want {
+			t.Errorf("Decode #%d: HadMismatch = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestAllowTypeMismatchMapOfStructs(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	in := `{
+		"a": {"name": "a", "count": 1},
+		"b": {"name": 2, "count": 2},
+		"c": "not an item",
+		"d": {"name": "d", "count": [4]},
+		"e": {"name": "e", "count": 5}
+	}`
+
+	t.Run("Map", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		got := map[string]Item{"z": {Name: "z"}}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		want := map[string]Item{
+			"a": {Name: "a", Count: 1},
+			"b": {Count: 2},
+			"c": {},
+			"d": {Name: "d"},
+			"e": {Name: "e", Count: 5},
+			"z": {Name: "z"},
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		if n := len(dec.Mismatches()); n != 3 {
+			t.Errorf("Decode: got %d mismatches, want 3", n)
+		}
+	})
+
+	t.Run("MapOfPointers", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		var got map[string]*Item
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		want := map[string]*Item{
+			"a": {Name: "a", Count: 1},
+			"b": {Count: 2},
+			"c": nil,
+			"d": {Name: "d"},
+			"e": {Name: "e", Count: 5},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	type T struct {
+		String string `json:"string"`
+		Int    int    `json:"int"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"string": "a", "int": 1} {"string": 2, "int": "b"} {"int": 3} {"string": `))
+	v := T{String: "seed", Int: -1}
+	for i, want := range [][]string{nil, {"string", "int"}, nil} {
+		mismatches, err := dec.Validate(&v)
+		if err != nil {
+			t.Fatalf("Validate #%d error: %v", i, err)
+		}
+		var fields []string
+		for _, m := range mismatches {
+			fields = append(fields, m.Field)
+		}
+		if !slices.Equal(fields, want) {
+			t.Errorf("Validate #%d mismatched fields = %q, want %q", i, fields, want)
+		}
+		if v != (T{String: "seed", Int: -1}) {
+			t.Fatalf("Validate #%d modified its argument: %+v", i, v)
+		}
+	}
+	if _, err := dec.Validate(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("Validate error: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := dec.Validate(v); err == nil {
+		t.Errorf("Validate with a non-pointer: got no error")
+	}
+
+	// Validate does not change the settings of the Decoder.
+	dec = NewDecoder(strings.NewReader(`{"int": "1"} {"int": "2"}`))
+	if _, err := dec.Validate(&v); err != nil {
+		t.Fatalf("Validate error: %v", err)
+	}
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(&v); !errors.As(err, &ute) {
+		t.Errorf("Decode after Validate: error %v, want UnmarshalTypeError", err)
+	}
+	if m := dec.Mismatches(); m != nil {
+		t.Errorf("Mismatches after Validate and Decode = %v, want nil", m)
+	}
+}
+
+func TestSetDuplicateKeyBestMatch(t *testing.T) {
+	type Inner struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type T struct {
+		Int   int   `json:"int"`
+		Inner Inner `json:"inner"`
+	}
+	tests := []struct {
+		CaseName
+		in         string
+		bestMatch  T
+		last       T
+		mismatches int // with SetDuplicateKeyBestMatch
+	}{{
+		CaseName:  Name(""),
+		in:        `{"int": 5, "int": "bad"}`,
+		bestMatch: T{Int: 5},
+		last:      T{},
+	}, {
+		CaseName:   Name(""),
+		in:         `{"int": "bad", "int": 5, "int": 6}`,
+		bestMatch:  T{Int: 5},
+		last:       T{Int: 6},
+		mismatches: 1,
+	}, {
+		CaseName:   Name(""),
+		in:         `{"int": "bad", "int": [1]}`,
+		bestMatch:  T{},
+		last:       T{},
+		mismatches: 2,
+	}, {
+		CaseName:   Name(""),
+		in:         `{"inner": {"a": 1, "b": "x"}, "inner": {"a": 2, "b": 3}, "inner": {"a": "y"}}`,
+		bestMatch:  T{Inner: Inner{A: 2, B: 3}},
+		last:       T{Inner: Inner{B: 3}},
+		mismatches: 1,
+	}, {
+		CaseName:  Name(""),
+		in:        `{"INT": 5, "int": "bad"}`,
+		bestMatch: T{Int: 5},
+		last:      T{},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, bestMatch := range []bool{false, true} {
+				dec := NewDecoder(strings.NewReader(tc.in))
+				dec.AllowTypeMismatch()
+				dec.RecordMismatches()
+				dec.SetDuplicateKeyBestMatch(bestMatch)
+				var got T
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("%s: Decode error: %v", tc.Where, err)
+				}
+				want := tc.last
+				if bestMatch {
+					want = tc.bestMatch
+					if n := len(dec.Mismatches()); n != tc.mismatches {
+						t.Errorf("%s: got %d mismatches, want %d", tc.Where, n, tc.mismatches)
+					}
+				}
+				if got != want {
+					t.Errorf("%s: Decode with best match %v:\n\tgot:  %+v\n\twant: %+v", tc.Where, bestMatch, got, want)
+				}
+			}
+		})
+	}
+}
+
+type (
+	namedSlice  []string
+	namedMap    map[string]int
+	namedStruct struct{ A int }
+	namedInt    int
+)
+
+func TestAllowTypeMismatchNamedTypes(t *testing.T) {
+	type T struct {
+		Slice  namedSlice  `json:"slice"`
+		Map    namedMap    `json:"map"`
+		Struct namedStruct `json:"struct"`
+		Int    namedInt    `json:"int"`
+	}
+	seed := T{
+		Slice:  namedSlice{"a"},
+		Map:    namedMap{"a": 1},
+		Struct: namedStruct{A: 1},
+		Int:    1,
+	}
+	tests := []struct {
+		CaseName
+		in string
+	}{
+		{Name(""), `{"slice": "a", "map": "a", "struct": "a", "int": "a"}`},
+		{Name(""), `{"slice": 1, "map": 1, "struct": 1, "int": 1.5}`},
+		{Name(""), `{"slice": {}, "map": [], "struct": [], "int": {}}`},
+		{Name(""), `{"slice": true, "map": true, "struct": true, "int": true}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			got := seed
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if !reflect.DeepEqual(got, T{}) {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: zero value", tc.Where, got)
+			}
+			var types []reflect.Type
+			for _, m := range dec.Mismatches() {
+				types = append(types, m.Type)
+			}
+			want := []reflect.Type{
+				reflect.TypeFor[namedSlice](),
+				reflect.TypeFor[namedMap](),
+				reflect.TypeFor[namedStruct](),
+				reflect.TypeFor[namedInt](),
+			}
+			if !slices.Equal(types, want) {
+				t.Errorf("%s: mismatched types = %v, want %v", tc.Where, types, want)
+			}
+		})
+	}
+}
+
+func TestWasMismatched(t *testing.T) {
+	type Inner struct {
+		Zip int `json:"zip"`
+	}
+	type T struct {
+		Int   int   `json:"int"`
+		Uint  uint  `json:"uint"`
+		Inner Inner `json:"inner"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"int": "1", "inner": {"zip": "x"}}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"int":       true,
+		"uint":      false, // absent
+		"inner":     false,
+		"inner.zip": true,
+		"zip":       false,
+	} {
+		if got := dec.WasMismatched(path); got != want {
+			t.Errorf("WasMismatched(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSetMismatchAsError(t *testing.T) {
+	type T struct {
+		String string `json:"string"`
+		Int    int    `json:"int"`
+		Bool   bool   `json:"bool"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"string": "a", "int": "1", "bool": true} {"string": 1, "int": "1", "bool": true} {"string": "c"}`))
+	dec.AllowTypeMismatch()
+	dec.SetMismatchAsError(true)
+
+	var v T
+	err := dec.Decode(&v)
+	var merr *MismatchError
+	if !errors.As(err, &merr) || len(merr.Mismatches) != 1 {
+		t.Fatalf("Decode error: %v, want MismatchError with 1 mismatch", err)
+	}
+	if want := "json: tolerated type mismatch: cannot unmarshal string into Go struct field T.int of type int"; err.Error() != want {
+		t.Errorf("Error:\n\tgot:  %s\n\twant: %s", err, want)
+	}
+	if want := (T{String: "a", Bool: true}); v != want {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+
+	v = T{}
+	err = dec.Decode(&v)
+	if want := "json: tolerated 2 type mismatches, the first: cannot unmarshal number into Go struct field T.string of type string"; err == nil || err.Error() != want {
+		t.Errorf("Error:\n\tgot:  %v\n\twant: %s", err, want)
+	}
+	var ute *UnmarshalTypeError
+	if !errors.As(err, &ute) || ute.Field != "string" {
+		t.Errorf("errors.As(%v, *UnmarshalTypeError) = %+v, want the first mismatch", err, ute)
+	}
+	if want := (T{Bool: true}); v != want {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+
+	if err := dec.Decode(&v); err != nil {
+		t.Errorf("Decode without mismatches: error %v", err)
+	}
+}
+
+func TestAllowTypeMismatchPeekThenDecode(t *testing.T) {
+	type Circle struct {
+		Radius float64 `json:"radius"`
+	}
+	type Rect struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+	in := `{"type": "circle", "radius": "big"}
+{"type": "rect", "width": 2, "height": 3}
+{"type": "rect", "width": [], "height": 4}`
+
+	// The envelopes are read as raw messages, and then each of them is decoded
+	// into the type named by its "type" key, with a decoder that is reset to
+	// read it and keeps its settings.
+	dec := NewDecoder(strings.NewReader(in))
+	shapeDec := NewDecoder(nil)
+	shapeDec.AllowTypeMismatch()
+	shapeDec.RecordMismatches()
+	var got []any
+	var mismatches []string
+	for dec.More() {
+		var raw RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		var peek struct {
+			Type string `json:"type"`
+		}
+		if err := Unmarshal(raw, &peek); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		var shape any
+		switch peek.Type {
+		case "circle":
+			shape = new(Circle)
+		case "rect":
+			shape = new(Rect)
+		default:
+			t.Fatalf("unknown shape %q", peek.Type)
+		}
+		shapeDec.Reset(bytes.NewReader(raw))
+		if err := shapeDec.Decode(shape); err != nil {
+			t.Fatalf("Decode %s error: %v", peek.Type, err)
+		}
+		got = append(got, reflect.ValueOf(shape).Elem().Interface())
+		for _, m := range shapeDec.Mismatches() {
+			mismatches = append(mismatches, peek.Type+"."+m.Field)
+		}
+	}
+	want := []any{Circle{}, Rect{Width: 2, Height: 3}, Rect{Height: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded shapes:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if want := []string{"circle.radius", "rect.width"}; !slices.Equal(mismatches, want) {
+		t.Errorf("mismatches = %q, want %q", mismatches, want)
+	}
+}
+
+func TestAllowTypeMismatchTopLevelContainers(t *testing.T) {
+	tests := []struct {
+		CaseName
+		in   string
+		ptr  func() any // returns a pointer to a seeded target
+		want any
+	}{{
+		CaseName: Name(""),
+		in:       `{}`,
+		ptr:      func() any { return &[]int{1, 2} },
+		want:     []int(nil),
+	}, {
+		CaseName: Name(""),
+		in:       `{"a": 1}`,
+		ptr:      func() any { return &[2]int{1, 2} },
+		want:     [2]int{},
+	}, {
+		CaseName: Name(""),
+		in:       `"abc"`,
+		ptr:      func() any { return &[]int{1, 2} },
+		want:     []int(nil),
+	}, {
+		CaseName: Name(""),
+		in:       `123`,
+		ptr:      func() any { return &map[string]int{"a": 1} },
+		want:     map[string]int(nil),
+	}, {
+		CaseName: Name(""),
+		in:       `[1, 2]`,
+		ptr:      func() any { return &map[string]int{"a": 1} },
+		want:     map[string]int(nil),
+	}, {
+		CaseName: Name(""),
+		in:       `true`,
+		ptr:      func() any { return &map[string]int{"a": 1} },
+		want:     map[string]int(nil),
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			ptr := tc.ptr()
+			if err := dec.Decode(ptr); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got := reflect.ValueOf(ptr).Elem().Interface(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s: Decode:\n\tgot:  %#v\n\twant: %#v", tc.Where, got, tc.want)
+			}
+			if !dec.HadMismatch() {
+				t.Errorf("%s: HadMismatch = false, want true", tc.Where)
+			}
+
+			var ute *UnmarshalTypeError
+			if err := NewDecoder(strings.NewReader(tc.in)).Decode(tc.ptr()); !errors.As(err, &ute) {
+				t.Errorf("%s: Decode without AllowTypeMismatch: error %v, want UnmarshalTypeError", tc.Where, err)
+			}
+		})
+	}
+}
+
+func TestToleratePaths(t *testing.T) {
+	type Item struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+	type Addr struct {
+		City string `json:"city"`
+		Zip  int    `json:"zip"`
+	}
+	type T struct {
+		Name  string `json:"name"`
+		Addr  Addr   `json:"addr"`
+		Items []Item `json:"items"`
+		Tags  []int  `json:"tags"`
+		Grid  [][]int
+	}
+	tests := []struct {
+		CaseName
+		paths   []string
+		in      string
+		want    T
+		wantErr string // Field of the expected UnmarshalTypeError
+	}{{
+		CaseName: Name("Listed"),
+		paths:    []string{"addr.zip", "items[].price"},
+		in:       `{"name": "n", "addr": {"city": "c", "zip": "z"}, "items": [{"name": "a", "price": "free"}, {"name": "b", "price": 2}]}`,
+		want:     T{Name: "n", Addr: Addr{City: "c"}, Items: []Item{{Name: "a"}, {Name: "b", Price: 2}}},
+	}, {
+		CaseName: Name("NotListed"),
+		paths:    []string{"addr.zip", "items[].price"},
+		in:       `{"addr": {"city": 1, "zip": "z"}}`,
+		wantErr:  "addr.city",
+	}, {
+		CaseName: Name("NotListedInArray"),
+		paths:    []string{"addr.zip", "items[].price"},
+		in:       `{"items": [{"name": 1, "price": 2}]}`,
+		wantErr:  "items.name",
+	}, {
+		CaseName: Name("WholeField"),
+		paths:    []string{"items", "tags"},
+		in:       `{"items": [{"name": 1, "price": "x"}, "y"], "tags": [1, "2", 3]}`,
+		want:     T{Items: []Item{{}, {}}, Tags: []int{1, 0, 3}},
+	}, {
+		CaseName: Name("ArrayOnly"),
+		paths:    []string{"tags[]"},
+		in:       `{"tags": "x"}`,
+		wantErr:  "tags",
+	}, {
+		CaseName: Name("NestedArrays"),
+		paths:    []string{"Grid[][]"},
+		in:       `{"Grid": [[1, "x"], [3]]}`,
+		want:     T{Grid: [][]int{{1, 0}, {3}}},
+	}, {
+		CaseName: Name("Prefix"),
+		paths:    []string{"add"},
+		in:       `{"addr": {"zip": "z"}}`,
+		wantErr:  "addr.zip",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.ToleratePaths(tc.paths...)
+			var got T
+			err := dec.Decode(&got)
+			if tc.wantErr != "" {
+				var ute *UnmarshalTypeError
+				if !errors.As(err, &ute) || ute.Field != tc.wantErr {
+					t.Fatalf("%s: Decode error: %v, want UnmarshalTypeError for field %s", tc.Where, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s: Decode:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, tc.want)
+			}
+		})
+	}
+
+	// A top-level array.
+	dec := NewDecoder(strings.NewReader(`[{"name": "a", "price": "x"}]`))
+	dec.AllowTypeMismatch()
+	dec.ToleratePaths("[].price")
+	var items []Item
+	if err := dec.Decode(&items); err != nil || !slices.Equal(items, []Item{{Name: "a"}}) {
+		t.Errorf("Decode = %+v, %v, want [{Name:a}] and no error", items, err)
+	}
+
+	// Without paths, every mismatch is tolerated again.
+	dec = NewDecoder(strings.NewReader(`{"addr": {"city": 1}}`))
+	dec.AllowTypeMismatch()
+	dec.ToleratePaths("name")
+	dec.ToleratePaths()
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Errorf("Decode after ToleratePaths(): error %v", err)
+	}
+}
+
+func TestAllowTypeMismatchBig(t *testing.T) {
+	type T struct {
+		Int      big.Int
+		IntPtr   *big.Int
+		Float    big.Float
+		FloatPtr *big.Float
+		Rat      *big.Rat
+	}
+	tests := []struct {
+		CaseName
+		in   string
+		want string // T encoded again
+		mism []string
+	}{{
+		CaseName: Name("Valid"),
+		in:       `{"Int": 12, "IntPtr": 3, "Float": "1.5", "FloatPtr": "-2", "Rat": "1/3"}`,
+		want:     `{"Int":12,"IntPtr":3,"Float":"1.5","FloatPtr":"-2","Rat":"1/3"}`,
+	}, {
+		CaseName: Name("Garbage"),
+		in:       `{"Int": "abc", "IntPtr": "12x", "Float": "abc", "FloatPtr": "1.5.5", "Rat": "1/0"}`,
+		want:     `{"Int":0,"IntPtr":0,"Float":"0","FloatPtr":"0","Rat":"0"}`,
+		mism:     []string{"Int", "IntPtr", "Float", "FloatPtr", "Rat"},
+	}, {
+		CaseName: Name("WrongKind"),
+		in:       `{"Int": 1.5, "IntPtr": [1], "Float": 1.5, "FloatPtr": {"x": 1}, "Rat": true}`,
+		want:     `{"Int":0,"IntPtr":0,"Float":"0","FloatPtr":"0","Rat":"0"}`,
+		mism:     []string{"Int", "IntPtr", "Float", "FloatPtr", "Rat"},
+	}, {
+		CaseName: Name("Null"),
+		in:       `{"Int": null, "IntPtr": null, "FloatPtr": null, "Rat": null}`,
+		want:     `{"Int":0,"IntPtr":null,"Float":"0","FloatPtr":null,"Rat":null}`,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			b, err := Marshal(&v)
+			if err != nil {
+				t.Fatalf("%s: Marshal error: %v", tc.Where, err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %s\n\twant: %s", tc.Where, b, tc.want)
+			}
+			var mism []string
+			for _, m := range dec.Mismatches() {
+				mism = append(mism, m.Field)
+			}
+			if !slices.Equal(mism, tc.mism) {
+				t.Errorf("%s: Mismatches fields:\n\tgot:  %q\n\twant: %q", tc.Where, mism, tc.mism)
+			}
+		})
+	}
+
+	// A value that cannot be parsed is kept in merge mode.
+	dec := NewDecoder(strings.NewReader(`{"Int": "abc", "Float": 7}`))
+	dec.AllowTypeMismatch()
+	dec.SetMergeMode(true)
+	var v T
+	v.Int.SetInt64(5)
+	v.Float.SetInt64(6)
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.Int.Int64() != 5 || v.Float.String() != "6" {
+		t.Errorf("Decode in merge mode = %v, %v, want 5, 6", &v.Int, &v.Float)
+	}
+
+	// Without tolerance, the errors are unchanged.
+	if err := Unmarshal([]byte(`{"Int": "abc"}`), new(T)); err == nil || !strings.Contains(err.Error(), "math/big") {
+		t.Errorf("Unmarshal error: %v, want math/big error", err)
+	}
+	var ute *UnmarshalTypeError
+	if err := Unmarshal([]byte(`{"Float": 1.5}`), new(T)); !errors.As(err, &ute) {
+		t.Errorf("Unmarshal error: %v, want UnmarshalTypeError", err)
+	}
+}
+
+func TestAllowTypeMismatchNet(t *testing.T) {
+	type T struct {
+		IP       net.IP         `json:"ip"`
+		Addr     netip.Addr     `json:"addr"`
+		AddrPort netip.AddrPort `json:"addrPort"`
+		Prefix   netip.Prefix   `json:"prefix"`
+		URL      *url.URL       `json:"url"`
+	}
+	tests := []struct {
+		CaseName
+		in   string
+		want string // T encoded again
+		mism []string
+	}{{
+		CaseName: Name("Valid"),
+		in:       `{"ip": "10.0.0.1", "addr": "::1", "addrPort": "1.2.3.4:80", "prefix": "10.0.0.0/8"}`,
+		want:     `{"ip":"10.0.0.1","addr":"::1","addrPort":"1.2.3.4:80","prefix":"10.0.0.0/8","url":null}`,
+	}, {
+		CaseName: Name("Garbage"),
+		in:       `{"ip": "10.0.0.256", "addr": "localhost", "addrPort": "1.2.3.4", "prefix": "10.0.0.0/33", "url": "http://[::1"}`,
+		want:     `{"ip":"","addr":"","addrPort":"","prefix":"","url":null}`,
+		mism:     []string{"ip", "addr", "addrPort", "prefix", "url"},
+	}, {
+		CaseName: Name("WrongKind"),
+		in:       `{"ip": [10, 0, 0, 1], "addr": 1, "addrPort": true, "prefix": {}, "url": 5}`,
+		want:     `{"ip":"","addr":"","addrPort":"","prefix":"","url":null}`,
+		mism:     []string{"ip", "addr", "addrPort", "prefix", "url"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			v := T{IP: net.IP{1, 2, 3, 4}, Addr: netip.IPv6Loopback()}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			b, err := Marshal(&v)
+			if err != nil {
+				t.Fatalf("%s: Marshal error: %v", tc.Where, err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %s\n\twant: %s", tc.Where, b, tc.want)
+			}
+			var mism []string
+			for _, m := range dec.Mismatches() {
+				mism = append(mism, m.Field)
+			}
+			if !slices.Equal(mism, tc.mism) {
+				t.Errorf("%s: Mismatches fields:\n\tgot:  %q\n\twant: %q", tc.Where, mism, tc.mism)
+			}
+		})
+	}
+
+	// A URL has no text form, but a coercion can parse it.
+	dec := NewDecoder(strings.NewReader(`{"url": "https://example.com/a"} {"url": "http://[::1"}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.RegisterCoercion(reflect.TypeFor[url.URL](), func(raw RawMessage) (any, error) {
+		var s string
+		if err := Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	})
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.URL == nil || v.URL.String() != "https://example.com/a" || dec.HadMismatch() {
+		t.Errorf("Decode with coercion: URL = %v, mismatch %v, want https://example.com/a, false", v.URL, dec.HadMismatch())
+	}
+	v = T{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.URL != nil || !dec.WasMismatched("url") {
+		t.Errorf("Decode with coercion: URL = %v, mismatch %v, want nil, true", v.URL, dec.HadMismatch())
+	}
+
+	// Without tolerance, the errors are unchanged.
+	var perr *net.ParseError
+	if err := Unmarshal([]byte(`{"ip": "10.0.0.256"}`), new(T)); !errors.As(err, &perr) {
+		t.Errorf("Unmarshal error: %v, want net.ParseError", err)
+	}
+	if err := Unmarshal([]byte(`{"addr": "localhost"}`), new(T)); err == nil {
+		t.Errorf("Unmarshal error: nil, want a netip error")
+	}
+}
+
+func TestMismatchInputOffset(t *testing.T) {
+	type T struct {
+		Int   int             `json:"int"`
+		Num   int             `json:"num,string"`
+		Ints  []int           `json:"ints"`
+		Inner struct{ X int } `json:"inner"`
+		Map   map[int]bool    `json:"map"`
+	}
+	in := `{"int": "x"} {"num": "1.5"} {"ints": [1, true]} {"inner": [1]} {"inner": {"X": {}}} {"map": {"1.5": true}}`
+	// The mismatched values, in order, each at the start of the value
+	// reported by its InputOffset.
+	want := []string{`"x"`, `"1.5"`, `true`, `[1]`, `{}`, `"1.5"`}
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	from := 0
+	for _, w := range want {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		ms := dec.Mismatches()
+		if len(ms) != 1 {
+			t.Fatalf("Mismatches = %+v, want one mismatch", ms)
+		}
+		off := from + strings.Index(in[from:], w)
+		if ms[0].InputOffset != int64(off) {
+			t.Errorf("Mismatch %s: InputOffset = %d, want %d", ms[0].Field, ms[0].InputOffset, off)
+		}
+		from = int(dec.InputOffset())
+	}
+}
+
+func TestAllowTypeMismatchUnsupportedType(t *testing.T) {
+	type T struct {
+		Chan    chan int
+		Func    func()
+		Complex complex128
+		Int     int
+	}
+	tests := []struct {
+		CaseName
+		in       string
+		wantType reflect.Type // nil if no error is expected
+	}{
+		{CaseName: Name(""), in: `{"Chan": 1, "Int": 2}`, wantType: reflect.TypeFor[chan int]()},
+		{CaseName: Name(""), in: `{"Chan": [1, 2], "Int": 2}`, wantType: reflect.TypeFor[chan int]()},
+		{CaseName: Name(""), in: `{"Func": {}, "Int": 2}`, wantType: reflect.TypeFor[func()]()},
+		{CaseName: Name(""), in: `{"Func": "f", "Int": 2}`, wantType: reflect.TypeFor[func()]()},
+		{CaseName: Name(""), in: `{"Complex": 1, "Int": 2}`, wantType: reflect.TypeFor[complex128]()},
+		{CaseName: Name(""), in: `{"Complex": true, "Int": 2}`, wantType: reflect.TypeFor[complex128]()},
+		{CaseName: Name(""), in: `{"Chan": null, "Func": null, "Int": 2}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			var v T
+			err := dec.Decode(&v)
+			if tc.wantType == nil {
+				if err != nil {
+					t.Fatalf("%s: Decode error: %v", tc.Where, err)
+				}
+			} else {
+				var ute *UnsupportedTypeError
+				if !errors.As(err, &ute) || ute.Type != tc.wantType {
+					t.Fatalf("%s: Decode error: %v, want UnsupportedTypeError for %v", tc.Where, err, tc.wantType)
+				}
+			}
+			if v.Int != 2 {
+				t.Errorf("%s: Int = %d, want 2", tc.Where, v.Int)
+			}
+			if ms := dec.Mismatches(); ms != nil {
+				t.Errorf("%s: Mismatches = %+v, want nil", tc.Where, ms)
+			}
+		})
+	}
+
+	// Without tolerance, the error is an UnmarshalTypeError as with Unmarshal.
+	var ute *UnmarshalTypeError
+	if err := Unmarshal([]byte(`{"Chan": 1}`), new(T)); !errors.As(err, &ute) {
+		t.Errorf("Unmarshal error: %v, want UnmarshalTypeError", err)
+	}
+}
+
+func TestIntFromFloat(t *testing.T) {
+	tests := []struct {
+		CaseName
+		in           string
+		ptr          any
+		intFromFloat bool
+		want         any
+		mismatch     bool
+	}{
+		{CaseName: Name(""), in: `1e3`, ptr: new(int), want: 0, mismatch: true},
+		{CaseName: Name(""), in: `1.5e1`, ptr: new(int), want: 0, mismatch: true},
+		{CaseName: Name(""), in: `1e-1`, ptr: new(int), want: 0, mismatch: true},
+		{CaseName: Name(""), in: `1000.0`, ptr: new(uint), want: uint(0), mismatch: true},
+		{CaseName: Name(""), in: `1e3`, ptr: new(int), intFromFloat: true, want: 1000},
+		{CaseName: Name(""), in: `1.5e1`, ptr: new(int), intFromFloat: true, want: 15},
+		{CaseName: Name(""), in: `1e-1`, ptr: new(int), intFromFloat: true, want: 0, mismatch: true},
+		{CaseName: Name(""), in: `1000.0`, ptr: new(uint), intFromFloat: true, want: uint(1000)},
+		{CaseName: Name(""), in: `-2E2`, ptr: new(int64), intFromFloat: true, want: int64(-200)},
+		{CaseName: Name(""), in: `-2E2`, ptr: new(uint64), intFromFloat: true, want: uint64(0), mismatch: true},
+		{CaseName: Name(""), in: `1e3`, ptr: new(int8), intFromFloat: true, want: int8(0), mismatch: true},
+		{CaseName: Name(""), in: `9007199254740993.0`, ptr: new(int64), intFromFloat: true, want: int64(9007199254740993)},
+		{CaseName: Name(""), in: `1e19`, ptr: new(uint64), intFromFloat: true, want: uint64(1e19)},
+		{CaseName: Name(""), in: `1e19`, ptr: new(int64), intFromFloat: true, want: int64(0), mismatch: true},
+		{CaseName: Name(""), in: `1e1000000`, ptr: new(int64), intFromFloat: true, want: int64(0), mismatch: true},
+		{CaseName: Name(""), in: `12`, ptr: new(int), intFromFloat: true, want: 12},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.SetIntFromFloat(tc.intFromFloat)
+			if err := dec.Decode(tc.ptr); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if got := reflect.ValueOf(tc.ptr).Elem().Interface(); got != tc.want {
+				t.Errorf("%s: Decode(%s) = %v, want %v", tc.Where, tc.in, got, tc.want)
+			}
+			if dec.HadMismatch() != tc.mismatch {
+				t.Errorf("%s: HadMismatch = %v, want %v", tc.Where, dec.HadMismatch(), tc.mismatch)
+			}
+		})
+	}
+
+	// The option also applies without tolerance.
+	dec := NewDecoder(strings.NewReader(`{"A": 1e3} {"A": 1e-1}`))
+	dec.SetIntFromFloat(true)
+	var v struct{ A int }
+	if err := dec.Decode(&v); err != nil || v.A != 1000 {
+		t.Errorf("Decode = %d, %v, want 1000 and no error", v.A, err)
+	}
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(&v); !errors.As(err, &ute) {
+		t.Errorf("Decode error: %v, want UnmarshalTypeError", err)
+	}
+}
+
+func TestMismatchResolver(t *testing.T) {
+	type Inner struct {
+		N int `json:"n"`
+	}
+	type T struct {
+		Int    int     `json:"int"`
+		Str    string  `json:"str"`
+		Float  float64 `json:"float"`
+		Inner  Inner   `json:"inner"`
+		Bad    int     `json:"bad"`
+		Absent int     `json:"absent"`
+	}
+	type call struct {
+		Path string
+		Type reflect.Type
+		Raw  string
+	}
+	var calls []call
+	resolver := func(path string, goType reflect.Type, raw RawMessage) (any, bool) {
+		calls = append(calls, call{path, goType, string(raw)})
+		switch path {
+		case "int":
+			return -1, true
+		case "str":
+			return string(raw), true
+		case "float":
+			return nil, true
+		case "inner.n":
+			return 7, true
+		case "bad":
+			return "not an int", true
+		}
+		return nil, false
+	}
+
+	dec := NewDecoder(strings.NewReader(`{"int": "x", "str": [1], "float": true, "inner": {"n": {}}, "bad": "y", "absent": false}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetMergeMode(true)
+	dec.SetMismatchResolver(resolver)
+	v := T{Float: 1.5, Bad: 2, Absent: 3}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	// The value of "bad" is not assignable and "absent" is not resolved, so
+	// they are kept by the merge mode.
+	want := T{Int: -1, Str: "[1]", Inner: Inner{N: 7}, Bad: 2, Absent: 3}
+	if v != want {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	wantCalls := []call{
+		{"int", reflect.TypeFor[int](), `"x"`},
+		{"str", reflect.TypeFor[string](), `[1]`},
+		{"float", reflect.TypeFor[float64](), `true`},
+		{"inner.n", reflect.TypeFor[int](), `{}`},
+		{"bad", reflect.TypeFor[int](), `"y"`},
+		{"absent", reflect.TypeFor[int](), `false`},
+	}
+	if !slices.Equal(calls, wantCalls) {
+		t.Errorf("resolver calls:\n\tgot:  %v\n\twant: %v", calls, wantCalls)
+	}
+	// Resolved values are still reported as mismatches.
+	if got := len(dec.Mismatches()); got != len(wantCalls) {
+		t.Errorf("len(Mismatches) = %d, want %d", got, len(wantCalls))
+	}
+
+	// Without tolerance, the resolver is not called.
+	calls = nil
+	dec = NewDecoder(strings.NewReader(`{"int": "x"}`))
+	dec.SetMismatchResolver(resolver)
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(new(T)); !errors.As(err, &ute) || calls != nil {
+		t.Errorf("Decode error: %v, resolver calls: %v, want UnmarshalTypeError and no calls", err, calls)
+	}
+
+	// Removing the resolver zeroes mismatched values again.
+	dec = NewDecoder(strings.NewReader(`{"int": "x"}`))
+	dec.AllowTypeMismatch()
+	dec.SetMismatchResolver(resolver)
+	dec.SetMismatchResolver(nil)
+	v = T{Int: 5}
+	if err := dec.Decode(&v); err != nil || v.Int != 0 {
+		t.Errorf("Decode = %d, %v, want 0 and no error", v.Int, err)
+	}
+}
+
+func TestAllowComments(t *testing.T) {
+	type T struct {
+		Name  string   `json:"name"`
+		Port  int      `json:"port"`
+		Tags  []string `json:"tags"`
+		Empty []int    `json:"empty"`
+		Obj   struct{} `json:"obj"`
+	}
+	in := `// configuration
+{
+	/* the name */ "name": "srv", // trailing
+	"port" /* key */ : /* value */ "8080",
+	"tags": [ "a", /* "b", */ "c" ] ,
+	"empty": [ /* none */ ],
+	"obj": { // nothing
+	}
+	/* end **/ }`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.SetAllowComments(true)
+	dec.AllowTypeMismatch()
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{Name: "srv", Tags: []string{"a", "c"}, Empty: []int{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if !dec.HadMismatch() {
+		t.Errorf("HadMismatch = false, want true")
+	}
+
+	// Comments between and after the values of a stream.
+	dec = NewDecoder(strings.NewReader("1 /* one */ 2// two\n3/**/ // end"))
+	dec.SetAllowComments(true)
+	var ns []int
+	for {
+		var n int
+		err := dec.Decode(&n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		ns = append(ns, n)
+	}
+	if !slices.Equal(ns, []int{1, 2, 3}) {
+		t.Errorf("Decode stream = %v, want [1 2 3]", ns)
+	}
+
+	// Comments are only allowed with SetAllowComments.
+	var syntaxErr *SyntaxError
+	if err := NewDecoder(strings.NewReader(`{/**/}`)).Decode(new(T)); !errors.As(err, &syntaxErr) {
+		t.Errorf("Decode without comments: error %v, want SyntaxError", err)
+	}
+	for _, in := range []string{`{/ }`, `{"a": 1 /* }`, `[1 / 2]`} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.SetAllowComments(true)
+		if err := dec.Decode(new(any)); err == nil {
+			t.Errorf("Decode(%#q): no error", in)
+		}
+	}
+	// Strings are not scanned for comments.
+	dec = NewDecoder(strings.NewReader(`"// not a /* comment"`))
+	dec.SetAllowComments(true)
+	var s string
+	if err := dec.Decode(&s); err != nil || s != "// not a /* comment" {
+		t.Errorf("Decode = %q, %v, want %q", s, err, "// not a /* comment")
+	}
+}
+
+func TestAllowTrailingCommas(t *testing.T) {
+	tests := []struct {
+		CaseName
+		in   string
+		want any
+	}{
+		{CaseName: Name(""), in: `[1, 2, 3,]`, want: []any{1.0, 2.0, 3.0}},
+		{CaseName: Name(""), in: `{"a": 1,}`, want: map[string]any{"a": 1.0}},
+		{CaseName: Name(""), in: `[[1,], {"a": [2,],}, [],]`, want: []any{[]any{1.0}, map[string]any{"a": []any{2.0}}, []any{}}},
+		{CaseName: Name(""), in: `{"a": {"b": true ,} , "c": [null ,
+		] ,
+		}`, want: map[string]any{"a": map[string]any{"b": true}, "c": []any{nil}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.SetAllowTrailingCommas(true)
+			var got any
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s: Decode:\n\tgot:  %#v\n\twant: %#v", tc.Where, got, tc.want)
+			}
+
+			// Without the option, trailing commas are syntax errors.
+			var syntaxErr *SyntaxError
+			if err := NewDecoder(strings.NewReader(tc.in)).Decode(new(any)); !errors.As(err, &syntaxErr) {
+				t.Errorf("%s: Decode without trailing commas: error %v, want SyntaxError", tc.Where, err)
+			}
+		})
+	}
+
+	// Typed values, with tolerance and comments.
+	type T struct {
+		Ints  []int          `json:"ints"`
+		Arr   [2]int         `json:"arr"`
+		Map   map[string]int `json:"map"`
+		Inner struct {
+			S string `json:"s"`
+		} `json:"inner"`
+	}
+	dec := NewDecoder(strings.NewReader(`{
+		"ints": [1, "x", 3,], // three
+		"arr": [4, 5, /* six */],
+		"map": {"a": 1, "b": 2, /* more */},
+		"inner": {"s": "y",},
+	}`))
+	dec.AllowTypeMismatch()
+	dec.SetAllowComments(true)
+	dec.SetAllowTrailingCommas(true)
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{Ints: []int{1, 0, 3}, Arr: [2]int{4, 5}, Map: map[string]int{"a": 1, "b": 2}}
+	want.Inner.S = "y"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+
+	// A comma alone is not a trailing comma.
+	for _, in := range []string{`[,]`, `{,}`, `[1,,]`, `{"a": 1,,}`} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.SetAllowTrailingCommas(true)
+		if err := dec.Decode(new(any)); err == nil {
+			t.Errorf("Decode(%#q): no error", in)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	type T struct {
+		Int      int           `json:"int"`
+		Str      int           `json:"str"`
+		Float    int           `json:"float"`
+		Small    int8          `json:"small"`
+		Duration time.Duration `json:"duration"`
+		Dup      int           `json:"dup"`
+		Kept     string        `json:"kept"`
+	}
+	in := `{
+		"int": "x", // comment
+		"str": "12",
+		"float": 1e2,
+		"small": 300,
+		"duration": "1m",
+		"dup": 5, "dup": "bad",
+		"kept": false,
+	}`
+	maxMismatches := 10
+	lenient := LenientConfig{
+		AllowTypeMismatch:     true,
+		RecordMismatches:      true,
+		MismatchAsError:       true,
+		MaxMismatches:         &maxMismatches,
+		StringCoercion:        true,
+		DurationStrings:       true,
+		IntFromFloat:          true,
+		OverflowMismatch:      true,
+		MergeMode:             true,
+		DuplicateKeyBestMatch: true,
+		AllowComments:         true,
+		AllowTrailingCommas:   true,
+	}
+	dec := NewDecoder(strings.NewReader(in))
+	dec.ApplyConfig(lenient)
+	v := T{Int: 1, Small: 2, Kept: "k"}
+	err := dec.Decode(&v)
+	var merr *MismatchError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Decode error: %v, want MismatchError", err)
+	}
+	want := T{Int: 1, Str: 12, Float: 100, Small: 2, Duration: time.Minute, Dup: 5, Kept: "k"}
+	if v != want {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	var fields []string
+	for _, m := range dec.Mismatches() {
+		fields = append(fields, m.Field)
+	}
+	if want := []string{"int", "small", "kept"}; !slices.Equal(fields, want) {
+		t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, want)
+	}
+
+	// The zero config turns everything off again.
+	dec.ApplyConfig(LenientConfig{})
+	dec.Reset(strings.NewReader(`{"int": "x"}`))
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(new(T)); !errors.As(err, &ute) {
+		t.Errorf("Decode with zero config: error %v, want UnmarshalTypeError", err)
+	}
+	dec.Reset(strings.NewReader(`{"int": 1, /* c */}`))
+	var syntaxErr *SyntaxError
+	if err := dec.Decode(new(T)); !errors.As(err, &syntaxErr) {
+		t.Errorf("Decode with zero config: error %v, want SyntaxError", err)
+	}
+
+	// MaxMismatches limits the tolerated mismatches, even to none.
+	for _, n := range []int{1, 0} {
+		dec = NewDecoder(strings.NewReader(`{"int": "x", "str": "y"}`))
+		dec.ApplyConfig(LenientConfig{AllowTypeMismatch: true, MaxMismatches: &n})
+		var tooMany *TooManyMismatchesError
+		if err := dec.Decode(new(T)); !errors.As(err, &tooMany) || tooMany.Limit != n {
+			t.Errorf("Decode with MaxMismatches %d: error %v, want TooManyMismatchesError", n, err)
+		}
+	}
+	dec = NewDecoder(strings.NewReader(`{"int": "x", "str": "y"}`))
+	dec.ApplyConfig(LenientConfig{AllowTypeMismatch: true})
+	if err := dec.Decode(new(T)); err != nil {
+		t.Errorf("Decode without MaxMismatches: error %v, want nil", err)
+	}
+}
+
+func TestDecoderConfig(t *testing.T) {
+	type Inner struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type T struct {
+		Int   int       `json:"int"`
+		Str   int       `json:"str"`
+		Time  time.Time `json:"time"`
+		Slice []int     `json:"slice"`
+		Inner Inner     `json:"inner"`
+	}
+	in := `{"int": "x", /* c */ "Str": "12", "time": "2024/03/04", "slice": {}, "inner": {"a": "x", "b": 2},}`
+
+	base := NewDecoder(nil)
+	base.AllowTypeMismatch()
+	base.RecordMismatches()
+	base.SetStringCoercion(true)
+	base.SetTimeLayouts([]string{"2006/01/02"})
+	base.SetEmptyCollectionsOnMismatch(true)
+	base.SetReportCaseFolding(true)
+	base.SetToleranceBudgetPerStruct(3)
+	base.SetAllowComments(true)
+	base.SetAllowTrailingCommas(true)
+	cfg := base.Config()
+
+	budget := 3
+	want := LenientConfig{
+		AllowTypeMismatch:          true,
+		RecordMismatches:           true,
+		ToleranceBudgetPerStruct:   &budget,
+		StringCoercion:             true,
+		EmptyCollectionsOnMismatch: true,
+		ReportCaseFolding:          true,
+		TimeLayouts:                []string{"2006/01/02"},
+		AllowComments:              true,
+		AllowTrailingCommas:        true,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("Config:\n\tgot:  %+v\n\twant: %+v", cfg, want)
+	}
+
+	// The config is a copy: changing it does not change the Decoder.
+	cfg.TimeLayouts[0] = time.RFC1123
+	if base.Config().TimeLayouts[0] != "2006/01/02" {
+		t.Errorf("Config shares TimeLayouts with the Decoder")
+	}
+	cfg.TimeLayouts[0] = "2006/01/02"
+
+	// A decoder configured with the config decodes as the original.
+	decode := func(dec *Decoder) (T, []TypeMismatch) {
+		t.Helper()
+		dec.Reset(strings.NewReader(in))
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		return v, dec.Mismatches()
+	}
+	wantV, wantMs := decode(base)
+	other := NewDecoder(nil)
+	other.ApplyConfig(cfg)
+	gotV, gotMs := decode(other)
+	if !reflect.DeepEqual(gotV, wantV) || wantV.Str != 12 || wantV.Slice == nil || wantV.Time.IsZero() {
+		t.Errorf("Decode with applied config:\n\tgot:  %+v\n\twant: %+v", gotV, wantV)
+	}
+	if !reflect.DeepEqual(gotMs, wantMs) || len(wantMs) != 4 {
+		t.Errorf("Mismatches with applied config:\n\tgot:  %+v\n\twant: %+v", gotMs, wantMs)
+	}
+	if got := other.Config(); !reflect.DeepEqual(got, cfg) {
+		t.Errorf("Config after ApplyConfig:\n\tgot:  %+v\n\twant: %+v", got, cfg)
+	}
+
+	// The config of a new decoder is the zero config.
+	if got := NewDecoder(nil).Config(); !reflect.DeepEqual(got, LenientConfig{}) {
+		t.Errorf("Config of a new Decoder = %+v, want the zero config", got)
+	}
+}
+
+func TestDecoderConfigValueSettings(t *testing.T) {
+	type T struct {
+		V    int `json:"v"`
+		Int  int `json:"int"`
+		Str  int `json:"str"`
+		Deep any `json:"deep"`
+	}
+
+	base := NewDecoder(nil)
+	base.AllowTypeMismatch()
+	base.RecordMismatches()
+	base.SetCumulativeMismatches(true)
+	base.SetMaxDepth(2)
+	base.ToleratePaths("int", "deep")
+	base.SetVersionField("v", map[string]Policy{"1": PolicyStrict})
+	base.SetDiscriminatorKey("kind")
+	cfg := base.Config()
+
+	want := LenientConfig{
+		AllowTypeMismatch:    true,
+		RecordMismatches:     true,
+		CumulativeMismatches: true,
+		MaxDepth:             2,
+		ToleratePaths:        []string{"int", "deep"},
+		VersionField:         "v",
+		VersionPolicies:      map[string]Policy{"1": PolicyStrict},
+		DiscriminatorKey:     "kind",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("Config:\n\tgot:  %+v\n\twant: %+v", cfg, want)
+	}
+	cfg.ToleratePaths[0] = "str"
+	cfg.VersionPolicies["2"] = PolicyStrict
+	if got := base.Config(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Config shares memory with the Decoder:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+
+	// The decoder does not share memory with the config either.
+	cfg = base.Config()
+	other := cfg.NewDecoder(nil)
+	cfg.ToleratePaths[0] = "str"
+	cfg.VersionPolicies["2"] = PolicyStrict
+	if got := other.Config(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Config after ApplyConfig:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+
+	// The decoders behave the same for each setting.
+	for _, dec := range []*Decoder{base, other} {
+		dec.Reset(strings.NewReader(`{"int": "x", "deep": [[1]]} {"int": "x"} {"v": 1, "int": "x"} {"str": "x"}`))
+		for i, wantErr := range []bool{false, false, true, true} {
+			if err := dec.Decode(new(T)); (err != nil) != wantErr {
+				t.Errorf("Decode %d: error %v, want error %v", i, err, wantErr)
+			}
+		}
+		var fields []string
+		for _, m := range dec.Mismatches() {
+			fields = append(fields, m.Field)
+		}
+		if want := []string{"[0].int", "[0].deep", "[1].int"}; !slices.Equal(fields, want) {
+			t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, want)
+		}
+	}
+
+	// A limit of zero mismatches is carried over as such, not as no limit.
+	for name, set := range map[string]func(*Decoder){
+		"SetMaxMismatches":            func(dec *Decoder) { dec.SetMaxMismatches(0) },
+		"SetToleranceBudgetPerStruct": func(dec *Decoder) { dec.SetToleranceBudgetPerStruct(0) },
+	} {
+		base = NewDecoder(strings.NewReader(`{"int": "x"}`))
+		base.AllowTypeMismatch()
+		set(base)
+		other = base.Config().NewDecoder(strings.NewReader(`{"int": "x"}`))
+		wantErr, err := base.Decode(new(T)), other.Decode(new(T))
+		if wantErr == nil || err == nil || err.Error() != wantErr.Error() {
+			t.Errorf("%s(0): Decode error with config %v, want %v", name, err, wantErr)
+		}
+	}
+
+	// Functions and registrations are not carried over.
+	base = NewDecoder(nil)
+	base.RegisterCoercion(reflect.TypeFor[int](), func(raw RawMessage) (any, error) { return 7, nil })
+	base.RegisterImpl(reflect.TypeFor[Shape](), "rect", reflect.TypeFor[Rect]())
+	for _, dec := range []*Decoder{base, base.Config().NewDecoder(nil)} {
+		dec.Reset(strings.NewReader(`{"int": "x"} {"type": "rect", "W": 1}`))
+		var v T
+		err := dec.Decode(&v)
+		var s Shape
+		err2 := dec.Decode(&s)
+		carried := err == nil && v.Int == 7 && err2 == nil && s != nil
+		if want := dec == base; carried != want {
+			t.Errorf("functions and registrations applied: %v, want %v (errors: %v, %v)", carried, want, err, err2)
+		}
+	}
+}
+
+func TestTimeLayouts(t *testing.T) {
+	type T struct {
+		Time time.Time  `json:"time"`
+		Ptr  *time.Time `json:"ptr"`
+	}
+	layouts := []string{time.RFC1123, "2006/01/02 15:04"}
+	date := time.Date(2024, 3, 4, 5, 6, 0, 0, time.UTC)
+	tests := []struct {
+		CaseName
+		in       string
+		want     time.Time
+		mismatch bool
+	}{
+		{CaseName: Name("RFC3339"), in: `"2024-03-04T05:06:00Z"`, want: date},
+		{CaseName: Name("RFC1123"), in: `"Mon, 04 Mar 2024 05:06:00 UTC"`, want: date},
+		{CaseName: Name("Custom"), in: `"2024/03/04 05:06"`, want: date},
+		{CaseName: Name("Invalid"), in: `"yesterday"`, mismatch: true},
+		{CaseName: Name("Number"), in: `1709528760`, mismatch: true},
+		{CaseName: Name("Array"), in: `["2024/03/04 05:06"]`, mismatch: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(`{"time": ` + tc.in + `, "ptr": ` + tc.in + `}`))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetTimeLayouts(layouts)
+			v := T{Time: time.Now()}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if !v.Time.Equal(tc.want) || v.Ptr == nil || !v.Ptr.Equal(tc.want) {
+				t.Errorf("%s: Decode = %v, %v, want %v", tc.Where, v.Time, v.Ptr, tc.want)
+			}
+			if got := dec.WasMismatched("time") && dec.WasMismatched("ptr"); got != tc.mismatch {
+				t.Errorf("%s: WasMismatched = %v, want %v", tc.Where, got, tc.mismatch)
+			}
+		})
+	}
+
+	// Without tolerance, a time that no layout parses is an error.
+	dec := NewDecoder(strings.NewReader(`{"time": "2024/03/04 05:06"} {"time": "yesterday"}`))
+	dec.SetTimeLayouts(layouts)
+	var v T
+	if err := dec.Decode(&v); err != nil || !v.Time.Equal(date) {
+		t.Errorf("Decode = %v, %v, want %v and no error", v.Time, err, date)
+	}
+	if err := dec.Decode(&v); err == nil {
+		t.Errorf("Decode: no error for an invalid time")
+	}
+
+	// Without layouts, an invalid time is an error even with tolerance, as
+	// before.
+	dec = NewDecoder(strings.NewReader(`{"time": "2024/03/04 05:06"}`))
+	dec.AllowTypeMismatch()
+	dec.SetTimeLayouts(layouts)
+	dec.SetTimeLayouts(nil)
+	if err := dec.Decode(&v); err == nil {
+		t.Errorf("Decode after SetTimeLayouts(nil): no error for an invalid time")
+	}
+}
+
+func TestReportCaseFolding(t *testing.T) {
+	type Inner struct {
+		Name string `json:"name"`
+	}
+	type T struct {
+		Int   int   `json:"int"`
+		Inner Inner `json:"inner"`
+		Other int
+	}
+	in := `{"Int": 1, "INNER": {"Name": 2}, "other": 3}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetReportCaseFolding(true)
+	var handled []TypeMismatch
+	dec.SetMismatchHandler(func(m TypeMismatch) { handled = append(handled, m) })
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := (T{Int: 1, Other: 3}); v != want {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	want := []TypeMismatch{
+		{Value: `object key "Int"`, Raw: RawMessage(`"Int"`), Type: reflect.TypeFor[int](), Offset: 2, Struct: "T", Field: "int", InputOffset: 1, Cause: CauseCaseFolding, ActualKind: KindString, Path: "int"},
+		{Value: `object key "INNER"`, Raw: RawMessage(`"INNER"`), Type: reflect.TypeFor[Inner](), Offset: 12, Struct: "T", Field: "inner", InputOffset: 11, Cause: CauseCaseFolding, ActualKind: KindString, Path: "inner"},
+		{Value: `object key "Name"`, Raw: RawMessage(`"Name"`), Type: reflect.TypeFor[string](), Offset: 22, Struct: "Inner", Field: "inner.name", InputOffset: 21, Cause: CauseCaseFolding, ActualKind: KindString, Path: "inner.name"},
+		{Value: "number", Raw: RawMessage(`2`), Type: reflect.TypeFor[string](), Offset: 30, Struct: "Inner", Field: "inner.name", InputOffset: 29, ActualKind: KindNumber, Path: "inner.name"},
+		{Value: `object key "other"`, Raw: RawMessage(`"other"`), Type: reflect.TypeFor[int](), Offset: 34, Struct: "T", Field: "Other", InputOffset: 33, Cause: CauseCaseFolding, ActualKind: KindString, Path: "Other"},
+	}
+	if got := dec.Mismatches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mismatches:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	if !reflect.DeepEqual(handled, want) {
+		t.Errorf("mismatch handler calls:\n\tgot:  %+v\n\twant: %+v", handled, want)
+	}
+
+	// Case folding is not a type mismatch.
+	dec = NewDecoder(strings.NewReader(`{"Int": 1}`))
+	dec.RecordMismatches()
+	dec.SetReportCaseFolding(true)
+	dec.SetMismatchAsError(true)
+	dec.SetMaxMismatches(0)
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if ms := dec.Mismatches(); len(ms) != 1 || ms[0].Cause != CauseCaseFolding {
+		t.Errorf("Mismatches = %+v, want one CauseCaseFolding", ms)
+	}
+	if dec.HadMismatch() || dec.WasMismatched("int") {
+		t.Errorf("HadMismatch or WasMismatched = true, want false")
+	}
+
+	// With a type mismatch too, MismatchError only has the type mismatch.
+	dec = NewDecoder(strings.NewReader(`{"Int": "x"}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetReportCaseFolding(true)
+	dec.SetMismatchAsError(true)
+	var merr *MismatchError
+	if err := dec.Decode(&v); !errors.As(err, &merr) || len(merr.Mismatches) != 1 || merr.Mismatches[0].Cause != CauseTypeMismatch {
+		t.Errorf("Decode error: %v, want MismatchError with one type mismatch", err)
+	}
+	if got := len(dec.Mismatches()); got != 2 {
+		t.Errorf("len(Mismatches) = %d, want 2", got)
+	}
+
+	// Case folding is not reported by default.
+	dec = NewDecoder(strings.NewReader(`{"Int": 1}`))
+	dec.RecordMismatches()
+	if err := dec.Decode(&v); err != nil || dec.Mismatches() != nil {
+		t.Errorf("Decode = %v, Mismatches = %+v, want no error and no mismatches", err, dec.Mismatches())
+	}
+}
+
+func TestEmptyCollectionsOnMismatch(t *testing.T) {
+	type T struct {
+		Slice []int            `json:"slice"`
+		Map   map[string]int   `json:"map"`
+		Bytes []byte           `json:"bytes"`
+		Elems [][]int          `json:"elems"`
+		Maps  map[string][]int `json:"maps"`
+		Ptr   *[]int           `json:"ptr"`
+	}
+	in := `{"slice": "x", "map": [1], "bytes": 5, "elems": [[1], {}], "maps": {"a": "y"}, "ptr": true}`
+	for _, empty := range []bool{false, true} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.SetEmptyCollectionsOnMismatch(empty)
+		v := T{Slice: []int{1}, Map: map[string]int{"a": 1}}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		b, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		want := `{"slice":null,"map":null,"bytes":null,"elems":[[1],null],"maps":{"a":null},"ptr":null}`
+		if empty {
+			want = `{"slice":[],"map":{},"bytes":"","elems":[[1],[]],"maps":{"a":[]},"ptr":[]}`
+		}
+		if string(b) != want {
+			t.Errorf("SetEmptyCollectionsOnMismatch(%v): Decode:\n\tgot:  %s\n\twant: %s", empty, b, want)
+		}
+	}
+}
+
+// marshalOnlyColor has a String and a MarshalJSON method, but no UnmarshalJSON
+// or UnmarshalText method, so it is decoded as an int.
+type marshalOnlyColor int
+
+func (c marshalOnlyColor) String() string {
+	switch c {
+	case 1:
+		return "red"
+	case 2:
+		return "green"
+	}
+	return "unknown"
+}
+
+func (c marshalOnlyColor) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(c.String())), nil
+}
+
+func TestAllowTypeMismatchMarshalOnlyType(t *testing.T) {
+	type T struct {
+		Color  marshalOnlyColor   `json:"color"`
+		Colors []marshalOnlyColor `json:"colors"`
+		Ptr    *marshalOnlyColor  `json:"ptr"`
+		After  int                `json:"after"`
+	}
+	// A value encoded by MarshalJSON is a string, which cannot be decoded.
+	in, err := Marshal(T{Color: 1, Colors: []marshalOnlyColor{2}, Ptr: new(marshalOnlyColor), After: 3})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var ute *UnmarshalTypeError
+	if err := Unmarshal(in, new(T)); !errors.As(err, &ute) {
+		t.Fatalf("Unmarshal error: %v, want UnmarshalTypeError", err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	got := T{Color: 2}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got.Color != 0 || !slices.Equal(got.Colors, []marshalOnlyColor{0}) || got.Ptr == nil || *got.Ptr != 0 || got.After != 3 {
+		t.Errorf("Decode = %+v, want zero colors and After 3", got)
+	}
+	var fields []string
+	for _, m := range dec.Mismatches() {
+		fields = append(fields, m.Field)
+	}
+	if want := []string{"color", "colors", "ptr"}; !slices.Equal(fields, want) {
+		t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, want)
+	}
+
+	// The underlying int is decoded as usual.
+	dec = NewDecoder(strings.NewReader(`{"color": 2, "colors": [1, "red"]}`))
+	dec.AllowTypeMismatch()
+	got = T{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got.Color != 2 || !slices.Equal(got.Colors, []marshalOnlyColor{1, 0}) {
+		t.Errorf("Decode = %+v, want color 2 and colors [1 0]", got)
+	}
+}
+
+func TestCumulativeMismatches(t *testing.T) {
+	type Addr struct {
+		Zip int `json:"zip"`
+	}
+	type T struct {
+		Name string `json:"name"`
+		Addr Addr   `json:"addr"`
+	}
+	in := `{"name": 1} {"name": "ok"} {"addr": {"zip": "x"}, "name": true} 5 {"name": "last"}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetCumulativeMismatches(true)
+	records := 0
+	for {
+		var v T
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		records++
+		if records == 3 && !dec.HadMismatch() {
+			t.Errorf("HadMismatch = false after record 3, want true")
+		}
+	}
+	var fields []string
+	for _, m := range dec.Mismatches() {
+		fields = append(fields, m.Field)
+	}
+	want := []string{"[0].name", "[2].addr.zip", "[2].name", "[3]"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Mismatches paths:\n\tgot:  %q\n\twant: %q", paths, want)
+	}
+	if !dec.WasMismatched("[2].addr.zip") || dec.WasMismatched("[1].name") {
+		t.Errorf("WasMismatched reports the wrong records")
+	}
+	if dec.HadMismatch() {
+		t.Errorf("HadMismatch = true after the last record, want false")
+	}
+
+	// ResetMismatches starts a new report, numbering records from 0 again.
+	dec.ResetMismatches()
+	if ms := dec.Mismatches(); ms != nil {
+		t.Errorf("Mismatches after ResetMismatches = %+v, want nil", ms)
+	}
+	dec.Reset(strings.NewReader(`{"name": "a"} {"name": 2}`))
+	for range 2 {
+		if err := dec.Decode(new(T)); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+	}
+	if ms := dec.Mismatches(); len(ms) != 1 || ms[0].Field != "[1].name" {
+		t.Errorf("Mismatches = %+v, want one for [1].name", ms)
+	}
+
+	// Without cumulative mismatches, only the last record is reported.
+	dec.SetCumulativeMismatches(false)
+	if ms := dec.Mismatches(); len(ms) != 1 || ms[0].Field != "name" || ms[0].Path != "name" {
+		t.Errorf("Mismatches = %+v, want one for name", ms)
+	}
+
+	// A path formatter is given the record index as the first segment.
+	dec = NewDecoder(strings.NewReader(`{"name": "a"} {"addr": {"zip": "x"}}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetCumulativeMismatches(true)
+	dec.SetPathFormatter(func(segments []PathSegment) string {
+		var b strings.Builder
+		for _, s := range segments {
+			if s.Array {
+				b.WriteString("/" + strconv.Itoa(s.Index))
+			} else {
+				b.WriteString("/" + s.Key)
+			}
+		}
+		return b.String()
+	})
+	for range 2 {
+		if err := dec.Decode(new(T)); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+	}
+	if ms := dec.Mismatches(); len(ms) != 1 || ms[0].Path != "/1/addr/zip" {
+		t.Errorf("Mismatches = %+v, want one at /1/addr/zip", ms)
+	}
+}
+
+func TestAllowTypeMismatchRawMessage(t *testing.T) {
+	type Raw = RawMessage
+	type Wrapper struct {
+		RawMessage
+	}
+	type T struct {
+		Raw     RawMessage            `json:"raw"`
+		Alias   Raw                   `json:"alias"`
+		Ptr     *RawMessage           `json:"ptr"`
+		Wrapper Wrapper               `json:"wrapper"`
+		Map     map[string]RawMessage `json:"map"`
+		Slice   []Raw                 `json:"slice"`
+	}
+	tests := []struct {
+		CaseName
+		in string
+	}{
+		{Name("Object"), `{"a": [1, "b"], "c": {"d": null}}`},
+		{Name("Array"), `[1, "two", {"three": 3}, [], null]`},
+		{Name("String"), `"string"`},
+		{Name("Number"), `-12.5e3`},
+		{Name("Bool"), `true`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			in := fmt.Sprintf(`{"raw": %[1]s, "alias": %[1]s, "ptr": %[1]s, "wrapper": %[1]s, "map": {"k": %[1]s}, "slice": [%[1]s]}`, tc.in)
+			dec := NewDecoder(strings.NewReader(in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			for name, raw := range map[string]RawMessage{
+				"raw":     got.Raw,
+				"alias":   got.Alias,
+				"ptr":     *got.Ptr,
+				"wrapper": got.Wrapper.RawMessage,
+				"map":     got.Map["k"],
+				"slice":   got.Slice[0],
+			} {
+				if string(raw) != tc.in {
+					t.Errorf("%s: %s = %s, want %s", tc.Where, name, raw, tc.in)
+				}
+			}
+			if ms := dec.Mismatches(); ms != nil {
+				t.Errorf("%s: Mismatches = %+v, want nil", tc.Where, ms)
+			}
+		})
+	}
+}
+
+func TestAllowTypeMismatchNilStructPointer(t *testing.T) {
+	type Inner struct {
+		Name string `json:"name"`
+	}
+	type T struct {
+		Inner *Inner  `json:"inner"`
+		Deep  **Inner `json:"deep"`
+	}
+	tests := []struct {
+		CaseName
+		in   string
+		want string // T encoded again
+		mism bool
+	}{
+		{CaseName: Name("String"), in: `"inner"`, want: `{"inner":null,"deep":null}`, mism: true},
+		{CaseName: Name("Number"), in: `12`, want: `{"inner":null,"deep":null}`, mism: true},
+		{CaseName: Name("Bool"), in: `false`, want: `{"inner":null,"deep":null}`, mism: true},
+		{CaseName: Name("Null"), in: `null`, want: `{"inner":null,"deep":null}`},
+		{CaseName: Name("Object"), in: `{"name": "x"}`, want: `{"inner":{"name":"x"},"deep":{"name":"x"}}`},
+		{CaseName: Name("EmptyObject"), in: `{}`, want: `{"inner":{"name":""},"deep":{"name":""}}`},
+		{CaseName: Name("MismatchedField"), in: `{"name": 1}`, want: `{"inner":{"name":""},"deep":{"name":""}}`, mism: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(`{"inner": ` + tc.in + `, "deep": ` + tc.in + `}`))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			b, err := Marshal(&v)
+			if err != nil {
+				t.Fatalf("%s: Marshal error: %v", tc.Where, err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("%s: Decode:\n\tgot:  %s\n\twant: %s", tc.Where, b, tc.want)
+			}
+			if got := dec.HadMismatch(); got != tc.mism {
+				t.Errorf("%s: HadMismatch = %v, want %v", tc.Where, got, tc.mism)
+			}
+		})
+	}
+
+	// A pointer that is already set keeps pointing to the same struct, which
+	// is set to its zero value.
+	inner := &Inner{Name: "old"}
+	v := T{Inner: inner}
+	dec := NewDecoder(strings.NewReader(`{"inner": 1}`))
+	dec.AllowTypeMismatch()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.Inner != inner || *inner != (Inner{}) {
+		t.Errorf("Decode: Inner = %+v, want the same pointer to a zero Inner", v.Inner)
+	}
+
+	// A coercion that provides a value allocates the struct as usual.
+	dec = NewDecoder(strings.NewReader(`{"inner": "named"}`))
+	dec.AllowTypeMismatch()
+	dec.RegisterCoercion(reflect.TypeFor[Inner](), func(raw RawMessage) (any, error) {
+		var name string
+		err := Unmarshal(raw, &name)
+		return Inner{Name: name}, err
+	})
+	v = T{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.Inner == nil || v.Inner.Name != "named" {
+		t.Errorf("Decode with coercion: Inner = %+v, want named", v.Inner)
+	}
+}
+
+func TestAllowLeadingZeros(t *testing.T) {
+	type T struct {
+		Int    int     `json:"int"`
+		Uint   uint8   `json:"uint"`
+		Float  float64 `json:"float"`
+		Any    any     `json:"any"`
+		Number Number  `json:"number"`
+		Raw    RawMessage
+	}
+	in := `{"int": -0123, "uint": 00, "float": 007.5e1, "any": 010, "number": -0042, "Raw": 0100}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.SetAllowLeadingZeros(true)
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{Int: -123, Uint: 0, Float: 75, Any: 10.0, Number: "-42", Raw: RawMessage(`0100`)}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+
+	// The values of an interface are decoded without reflection.
+	dec = NewDecoder(strings.NewReader(`[00, 01, -00.5]`))
+	dec.SetAllowLeadingZeros(true)
+	dec.UseNumber()
+	var a any
+	if err := dec.Decode(&a); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := []any{Number("0"), Number("1"), Number("-0.5")}; !reflect.DeepEqual(a, want) {
+		t.Errorf("Decode:\n\tgot:  %v\n\twant: %v", a, want)
+	}
+
+	// A mismatch is still tolerated, or reported, as usual.
+	dec = NewDecoder(strings.NewReader(`{"uint": 01.5, "int": 01}`))
+	dec.ApplyConfig(LenientConfig{AllowTypeMismatch: true, RecordMismatches: true, AllowLeadingZeros: true})
+	v = T{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.Int != 1 || !dec.WasMismatched("uint") {
+		t.Errorf("Decode = %+v, mismatches %+v, want int 1 and uint mismatched", v, dec.Mismatches())
+	}
+
+	// Leading zeros are a syntax error by default.
+	for _, in := range []string{`0123`, `00`, `-01`, `[1, 02]`} {
+		var syntaxErr *SyntaxError
+		if err := Unmarshal([]byte(in), new(any)); !errors.As(err, &syntaxErr) {
+			t.Errorf("Unmarshal(%s) error: %v, want SyntaxError", in, err)
+		}
+	}
+}
+
+func TestAllowNonFiniteFloats(t *testing.T) {
+	type T struct {
+		Float   float64 `json:"float"`
+		Float32 float32 `json:"float32"`
+		Any     any     `json:"any"`
+		Int     int     `json:"int"`
+		Uint    uint    `json:"uint"`
+	}
+	tests := []struct {
+		CaseName
+		in   string
+		want float64
+	}{
+		{Name("NaN"), `NaN`, math.NaN()},
+		{Name("Infinity"), `Infinity`, math.Inf(1)},
+		{Name("NegativeInfinity"), `-Infinity`, math.Inf(-1)},
+	}
+	same := func(got, want float64) bool {
+		return got == want || math.IsNaN(got) && math.IsNaN(want)
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			in := fmt.Sprintf(`{"float": %[1]s, "float32": %[1]s, "any": %[1]s, "int": %[1]s, "uint": %[1]s}`, tc.in)
+			dec := NewDecoder(strings.NewReader(in))
+			dec.SetAllowNonFiniteFloats(true)
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			v := T{Int: 1, Uint: 2}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			f, _ := v.Any.(float64)
+			if !same(v.Float, tc.want) || !same(float64(v.Float32), tc.want) || !same(f, tc.want) {
+				t.Errorf("%s: Decode = %+v, want floats %v", tc.Where, v, tc.want)
+			}
+			if v.Int != 0 || v.Uint != 0 {
+				t.Errorf("%s: Decode = %+v, want zero integers", tc.Where, v)
+			}
+			var fields []string
+			for _, m := range dec.Mismatches() {
+				fields = append(fields, m.Field)
+			}
+			if want := []string{"int", "uint"}; !slices.Equal(fields, want) {
+				t.Errorf("%s: Mismatches fields:\n\tgot:  %q\n\twant: %q", tc.Where, fields, want)
+			}
+
+			// Without tolerance, an integer target is an UnmarshalTypeError.
+			dec = NewDecoder(strings.NewReader(`{"int": ` + tc.in + `}`))
+			dec.SetAllowNonFiniteFloats(true)
+			var ute *UnmarshalTypeError
+			if err := dec.Decode(new(T)); !errors.As(err, &ute) {
+				t.Errorf("%s: Decode error: %v, want UnmarshalTypeError", tc.Where, err)
+			}
+
+			// The literals are a syntax error by default.
+			var syntaxErr *SyntaxError
+			if err := Unmarshal([]byte(in), new(T)); !errors.As(err, &syntaxErr) {
+				t.Errorf("%s: Unmarshal error: %v, want SyntaxError", tc.Where, err)
+			}
+		})
+	}
+
+	// A misspelled literal is still a syntax error.
+	for _, in := range []string{`Nan`, `[Inf]`, `-NaN`, `[Infinite]`} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.SetAllowNonFiniteFloats(true)
+		var syntaxErr *SyntaxError
+		if err := dec.Decode(new(any)); !errors.As(err, &syntaxErr) {
+			t.Errorf("Decode(%s) error: %v, want SyntaxError", in, err)
+		}
+	}
+}
+
+func TestAllowTypeMismatchSliceReuse(t *testing.T) {
+	const in = `[1, "x", 3]`
+	tests := []struct {
+		CaseName
+		slice []int
+	}{
+		{Name("Nil"), nil},
+		{Name("Capacity"), make([]int, 0, 10)},
+		{Name("Shorter"), []int{7}},
+		{Name("Longer"), []int{7, 8, 9, 10, 11}},
+		{Name("SameLength"), make([]int, 3)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			got := tc.slice
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if want := []int{1, 0, 3}; !slices.Equal(got, want) {
+				t.Errorf("%s: Decode = %v, want %v", tc.Where, got, want)
+			}
+			if cap(tc.slice) >= 3 && &got[0] != &tc.slice[:1][0] {
+				t.Errorf("%s: Decode did not reuse the backing array", tc.Where)
+			}
+			if ms := dec.Mismatches(); len(ms) != 1 || string(ms[0].Raw) != `"x"` {
+				t.Errorf("%s: Mismatches = %+v, want one for \"x\"", tc.Where, ms)
+			}
+		})
+	}
+
+	// An array keeps its length: the elements that the input does not have
+	// are zeroed, and the mismatched one too.
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	arr := [5]int{5, 5, 5, 5, 5}
+	if err := dec.Decode(&arr); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := [5]int{1, 0, 3, 0, 0}; arr != want {
+		t.Errorf("Decode = %v, want %v", arr, want)
+	}
+}
+
+func TestAllowTypeMismatchPointerElements(t *testing.T) {
+	type Item struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	const in = `[{"a": 1}, 5, "x", null, [1], {"a": "x", "b": "y"}, true]`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	var got []*Item
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := []*Item{{A: 1}, nil, nil, nil, nil, {B: "y"}, nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"[1]", "[2]", "[4]", "[5].a", "[6]"}; !slices.Equal(paths, want) {
+		t.Errorf("Mismatches paths:\n\tgot:  %q\n\twant: %q", paths, want)
+	}
+
+	// The pointers already in the slice are reused, as without tolerance, so
+	// a mismatched element points to a zero value instead of being nil.
+	dec = NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	existing := &Item{A: 8, B: "z"}
+	got = []*Item{nil, existing}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if len(got) != 7 || got[1] != existing || *existing != (Item{}) || got[2] != nil || got[4] != nil {
+		t.Errorf("Decode over existing pointers = %+v", got)
+	}
+}
+
+func TestMismatchActualKind(t *testing.T) {
+	type T struct {
+		Int    int             `json:"int"`
+		Bool   bool            `json:"bool"`
+		String string          `json:"string"`
+		Slice  []int           `json:"slice"`
+		Struct struct{ A int } `json:"struct"`
+		Map    map[int]int     `json:"map"`
+	}
+	tests := []struct {
+		CaseName
+		in    string
+		field string
+		want  Kind
+	}{
+		{Name(""), `{"int": "1"}`, "int", KindString},
+		{Name(""), `{"int": 1.5}`, "int", KindNumber},
+		{Name(""), `{"string": 1}`, "string", KindNumber},
+		{Name(""), `{"string": true}`, "string", KindBool},
+		{Name(""), `{"bool": "true"}`, "bool", KindString},
+		{Name(""), `{"bool": 0}`, "bool", KindNumber},
+		{Name(""), `{"int": [1]}`, "int", KindArray},
+		{Name(""), `{"slice": {"a": 1}}`, "slice", KindObject},
+		{Name(""), `{"struct": "A"}`, "struct", KindString},
+		{Name(""), `{"struct": [1]}`, "struct", KindArray},
+		{Name(""), `{"map": {"x": 1}}`, "map", KindString}, // the key
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			if err := dec.Decode(new(T)); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			ms := dec.Mismatches()
+			if len(ms) != 1 || ms[0].Field != tc.field {
+				t.Fatalf("%s: Mismatches = %+v, want one for %s", tc.Where, ms, tc.field)
+			}
+			if ms[0].ActualKind != tc.want {
+				t.Errorf("%s: ActualKind = %v, want %v", tc.Where, ms[0].ActualKind, tc.want)
+			}
+		})
+	}
+
+	for k, want := range map[Kind]string{
+		KindInvalid: "invalid",
+		KindNull:    "null",
+		KindBool:    "bool",
+		KindNumber:  "number",
+		KindString:  "string",
+		KindArray:   "array",
+		KindObject:  "object",
+		Kind(100):   "Kind(100)",
+	} {
+		if got := k.String(); got != want {
+			t.Errorf("Kind(%d).String() = %q, want %q", int(k), got, want)
+		}
+	}
+}
+
+func TestAllowTypeMismatchContainers(t *testing.T) {
+	type T struct {
+		Map    map[string]int  `json:"m"`
+		Slice  []int           `json:"s"`
+		Array  [2]int          `json:"a"`
+		Struct struct{ X int } `json:"st"`
+		After  int             `json:"after"`
+	}
+	in := `{"m": [1, 2, 3], "s": {"a": 1}, "a": {"b": [2]}, "st": [{"X": 1}], "after": 5}`
+	for _, empty := range []bool{false, true} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		dec.SetEmptyCollectionsOnMismatch(empty)
+		v := T{Map: map[string]int{"old": 1}, Slice: []int{1}, Array: [2]int{1, 2}}
+		v.Struct.X = 3
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode with empty collections %v: error %v", empty, err)
+		}
+		if empty != (v.Map != nil) || len(v.Map) != 0 {
+			t.Errorf("Decode with empty collections %v: Map = %#v", empty, v.Map)
+		}
+		if empty != (v.Slice != nil) || len(v.Slice) != 0 {
+			t.Errorf("Decode with empty collections %v: Slice = %#v", empty, v.Slice)
+		}
+		if v.Array != [2]int{} || v.Struct.X != 0 || v.After != 5 {
+			t.Errorf("Decode with empty collections %v: %+v", empty, v)
+		}
+		var got []string
+		for _, m := range dec.Mismatches() {
+			got = append(got, m.Field+" "+m.ActualKind.String())
+		}
+		if want := []string{"m array", "s object", "a object", "st array"}; !slices.Equal(got, want) {
+			t.Errorf("Decode with empty collections %v: mismatches:\n\tgot:  %q\n\twant: %q", empty, got, want)
+		}
+	}
+}
+
+// EmbeddedCustom is an Unmarshaler that accepts only objects, to be embedded
+// by pointer.
+type EmbeddedCustom struct {
+	V int
+}
+
+func (c *EmbeddedCustom) UnmarshalJSON(b []byte) error {
+	if b[0] != '{' {
+		return errors.New("EmbeddedCustom: not an object")
+	}
+	c.V = len(b)
+	return nil
+}
+
+func TestAllowTypeMismatchEmbeddedUnmarshaler(t *testing.T) {
+	type T struct {
+		*EmbeddedCustom `json:"custom"`
+		Name            string `json:"name"`
+	}
+	tests := []struct {
+		CaseName
+		in       string
+		merge    bool
+		want     *EmbeddedCustom
+		mismatch bool
+	}{
+		{CaseName: Name("Valid"), in: `{}`, want: &EmbeddedCustom{V: 2}},
+		{CaseName: Name("Invalid"), in: `"bad"`, mismatch: true},
+		{CaseName: Name("Array"), in: `[1]`, mismatch: true},
+		{CaseName: Name("InvalidMerge"), in: `1`, merge: true, mismatch: true},
+		{CaseName: Name("Null"), in: `null`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(`{"custom": ` + tc.in + `, "name": "x"}`))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetMergeMode(tc.merge)
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if v.Name != "x" || !reflect.DeepEqual(v.EmbeddedCustom, tc.want) {
+				t.Errorf("%s: Decode = {%+v %q}, want {%+v \"x\"}", tc.Where, v.EmbeddedCustom, v.Name, tc.want)
+			}
+			if tc.mismatch != dec.WasMismatched("custom") {
+				t.Errorf("%s: WasMismatched(custom) = %v, want %v", tc.Where, !tc.mismatch, tc.mismatch)
+			}
+		})
+	}
+
+	// Once the pointer is set, T is an Unmarshaler through it, as without
+	// tolerance.
+	dec := NewDecoder(strings.NewReader(`{"custom": "bad", "name": "x"}`))
+	dec.AllowTypeMismatch()
+	v := T{EmbeddedCustom: new(EmbeddedCustom)}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if v.V != len(`{"custom": "bad", "name": "x"}`) || v.Name != "" {
+		t.Errorf("Decode = {%+v %q}, want the whole object given to UnmarshalJSON", v.EmbeddedCustom, v.Name)
+	}
+}
+
+func TestDecodePartial(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type T struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+		Items []Item `json:"items"`
+		Tags  map[string]string
+	}
+	const full = `{"name": "list", "count": "x", "items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}], "Tags": {"k": "v"}}`
+	tests := []struct {
+		CaseName
+		in   string
+		want T
+		mism int
+	}{{
+		CaseName: Name("Complete"),
+		in:       full,
+		want:     T{Name: "list", Items: []Item{{1, "a"}, {2, "b"}}, Tags: map[string]string{"k": "v"}},
+		mism:     1,
+	}, {
+		CaseName: Name("InSecondItem"),
+		in:       full[:strings.Index(full, `"b"`)],
+		want:     T{Name: "list", Items: []Item{{1, "a"}, {ID: 2}}},
+		mism:     1,
+	}, {
+		CaseName: Name("InKey"),
+		in:       full[:strings.Index(full, `"items"`)+3],
+		want:     T{Name: "list"},
+		mism:     1,
+	}, {
+		CaseName: Name("AfterOpenArray"),
+		in:       full[:strings.Index(full, `[`)+1],
+		want:     T{Name: "list", Items: []Item{}},
+		mism:     1,
+	}, {
+		CaseName: Name("InNumber"),
+		in:       `{"name": "n", "count": 12`,
+		want:     T{Name: "n"},
+	}, {
+		CaseName: Name("SyntaxError"),
+		in:       `{"name": "n", "count": 3, "items": [{"id": 1}, {"id": x}]}`,
+		want:     T{Name: "n", Count: 3, Items: []Item{{ID: 1}, {}}},
+	}, {
+		CaseName: Name("Empty"),
+		in:       `{`,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			var got T
+			mismatches, err := dec.DecodePartial(&got)
+			if tc.in == full {
+				if err != nil {
+					t.Fatalf("%s: DecodePartial error: %v", tc.Where, err)
+				}
+			} else if err == nil {
+				t.Fatalf("%s: DecodePartial error: nil, want an error", tc.Where)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s: DecodePartial:\n\tgot:  %#v\n\twant: %#v", tc.Where, got, tc.want)
+			}
+			if len(mismatches) != tc.mism {
+				t.Errorf("%s: DecodePartial: %d mismatches, want %d", tc.Where, len(mismatches), tc.mism)
+			}
+			if tc.in != full {
+				if err2 := dec.Decode(new(T)); err2 != err {
+					t.Errorf("%s: Decode after DecodePartial error: %v, want %v", tc.Where, err2, err)
+				}
+			}
+		})
+	}
+
+	// A top-level literal is not salvaged.
+	var s string
+	if ms, err := NewDecoder(strings.NewReader(`"abc`)).DecodePartial(&s); err != io.ErrUnexpectedEOF || ms != nil || s != "" {
+		t.Errorf("DecodePartial = %q, %v, %v, want \"\", nil, %v", s, ms, err, io.ErrUnexpectedEOF)
+	}
+
+	// The salvaged part is decoded as by Decode, with its errors.
+	in := `{"count": "x", "name": 1, "items": [{"id": 1}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.SetMaxMismatches(1)
+	_, err := dec.DecodePartial(new(T))
+	var tooMany *TooManyMismatchesError
+	if !errors.As(err, &tooMany) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("DecodePartial over the mismatch limit: error %v, want TooManyMismatchesError and %v", err, io.ErrUnexpectedEOF)
+	}
+	if err := dec.Decode(new(T)); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode after DecodePartial error: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	dec = NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.SetMismatchAsError(true)
+	var merr *MismatchError
+	if _, err := dec.DecodePartial(new(T)); !errors.As(err, &merr) || len(merr.Mismatches) != 2 {
+		t.Errorf("DecodePartial with SetMismatchAsError: error %v, want MismatchError with 2 mismatches", err)
+	}
+
+	// Without tolerance, a type error does not stop the decoding.
+	var v T
+	_, err = NewDecoder(strings.NewReader(full)).DecodePartial(&v)
+	var ute *UnmarshalTypeError
+	if !errors.As(err, &ute) || v.Name != "list" || len(v.Items) != 2 {
+		t.Errorf("DecodePartial = %+v, %v, want the value and an UnmarshalTypeError", v, err)
+	}
+}
+
+func TestAllowTypeMismatchUnexportedFields(t *testing.T) {
+	type inner struct {
+		Promoted int `json:"promoted"`
+		hidden   int
+	}
+	type T struct {
+		inner
+		Name   string `json:"name"`
+		name   string
+		Count  int `json:"count"`
+		count  int
+		secret map[string]int
+		Last   bool `json:"last"`
+	}
+	in := `{"promoted": "x", "name": 1, "count": [2], "secret": {"a": 1}, "hidden": 3, "last": true}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	v := T{inner: inner{Promoted: 1, hidden: 2}, Name: "n", name: "kept", Count: 5, count: 6}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{inner: inner{hidden: 2}, name: "kept", count: 6, Last: true}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	var fields []string
+	for _, m := range dec.Mismatches() {
+		fields = append(fields, m.Field)
+	}
+	if want := []string{"promoted", "name", "count"}; !slices.Equal(fields, want) {
+		t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, want)
+	}
+}
+
+func TestAllowTypeMismatchQuotedOption(t *testing.T) {
+	type T struct {
+		Int   int     `json:"int,string"`
+		Bool  bool    `json:"bool,string"`
+		Float float64 `json:"float,string"`
+		Last  string  `json:"last"`
+	}
+	tests := []struct {
+		CaseName
+		in    string
+		want  T
+		field string
+		kind  Kind
+	}{
+		{Name(""), `{"int": "12", "bool": "true", "float": "1.5", "last": "x"}`, T{12, true, 1.5, "x"}, "", KindInvalid},
+		{Name(""), `{"int": "notanumber", "last": "x"}`, T{Last: "x"}, "int", KindString},
+		{Name(""), `{"int": 123, "last": "x"}`, T{Last: "x"}, "int", KindNumber},
+		{Name(""), `{"int": "", "last": "x"}`, T{Last: "x"}, "int", KindString},
+		{Name(""), `{"int": "1.5", "last": "x"}`, T{Last: "x"}, "int", KindNumber}, // the quoted literal
+		{Name(""), `{"int": [1], "last": "x"}`, T{Last: "x"}, "int", KindArray},
+		{Name(""), `{"bool": "yes", "last": "x"}`, T{Last: "x"}, "bool", KindString},
+		{Name(""), `{"bool": true, "last": "x"}`, T{Last: "x"}, "bool", KindBool},
+		{Name(""), `{"float": "\"1\"", "last": "x"}`, T{Last: "x"}, "float", KindString},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			v := T{Int: 7, Bool: true, Float: 2}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			if tc.field == "" {
+				if ms := dec.Mismatches(); len(ms) != 0 {
+					t.Fatalf("%s: Mismatches = %+v, want none", tc.Where, ms)
+				}
+				if v != tc.want {
+					t.Errorf("%s: Decode = %+v, want %+v", tc.Where, v, tc.want)
+				}
+				return
+			}
+			// Only the mismatched field is zeroed.
+			want := T{Int: 7, Bool: true, Float: 2, Last: "x"}
+			switch tc.field {
+			case "int":
+				want.Int = 0
+			case "bool":
+				want.Bool = false
+			case "float":
+				want.Float = 0
+			}
+			if v != want {
+				t.Errorf("%s: Decode = %+v, want %+v", tc.Where, v, want)
+			}
+			ms := dec.Mismatches()
+			if len(ms) != 1 || ms[0].Field != tc.field {
+				t.Fatalf("%s: Mismatches = %+v, want one for %s", tc.Where, ms, tc.field)
+			}
+			if ms[0].ActualKind != tc.kind {
+				t.Errorf("%s: ActualKind = %v, want %v", tc.Where, ms[0].ActualKind, tc.kind)
+			}
+		})
+	}
+
+	// Without tolerance, misuse of the option is still an error.
+	for _, in := range []string{`{"int": "notanumber"}`, `{"int": 123}`} {
+		if err := Unmarshal([]byte(in), new(T)); err == nil {
+			t.Errorf("Unmarshal(%s) error: nil, want an error", in)
+		}
+	}
+}
+
+func TestMismatchPathFormatter(t *testing.T) {
+	type Item struct {
+		Price int   `json:"price"`
+		Tags  []int `json:"tags"`
+	}
+	type T struct {
+		Items []Item `json:"items"`
+		Name  string `json:"name"`
+	}
+	jsonPath := func(segments []PathSegment) string {
+		var b strings.Builder
+		b.WriteString("$")
+		for _, s := range segments {
+			if s.Array {
+				fmt.Fprintf(&b, "[%d]", s.Index)
+			} else {
+				b.WriteString("." + s.Key)
+			}
+		}
+		return b.String()
+	}
+	slashPath := func(segments []PathSegment) string {
+		var b strings.Builder
+		for _, s := range segments {
+			if s.Array {
+				fmt.Fprintf(&b, "/%d", s.Index)
+			} else {
+				b.WriteString("/" + s.Key)
+			}
+		}
+		return b.String()
+	}
+	in := `{"items": [{"price": 1}, {"price": 2}, {"price": "x", "tags": [1, "a"]}], "name": 5}`
+	tests := []struct {
+		CaseName
+		format func([]PathSegment) string
+		want   []string
+	}{
+		{Name("Default"), nil, []string{"items[2].price", "items[2].tags[1]", "name"}},
+		{Name("JSONPath"), jsonPath, []string{"$.items[2].price", "$.items[2].tags[1]", "$.name"}},
+		{Name("Slash"), slashPath, []string{"/items/2/price", "/items/2/tags/1", "/name"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetPathFormatter(tc.format)
+			if err := dec.Decode(new(T)); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			var paths, fields []string
+			for _, m := range dec.Mismatches() {
+				paths = append(paths, m.Path)
+				fields = append(fields, m.Field)
+			}
+			if !slices.Equal(paths, tc.want) {
+				t.Errorf("%s: Path:\n\tgot:  %q\n\twant: %q", tc.Where, paths, tc.want)
+			}
+			// The formatter does not change Field.
+			if want := []string{"items.price", "items.tags", "name"}; !slices.Equal(fields, want) {
+				t.Errorf("%s: Field:\n\tgot:  %q\n\twant: %q", tc.Where, fields, want)
+			}
+		})
+	}
+
+	// The elements of a top-level array, and the whole value.
+	for in, want := range map[string]string{`[1, "a"]`: "$[1]", `"a"`: "$"} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		dec.SetPathFormatter(jsonPath)
+		if err := dec.Decode(new([]int)); err != nil {
+			t.Fatalf("Decode(%s) error: %v", in, err)
+		}
+		if ms := dec.Mismatches(); len(ms) != 1 || ms[0].Path != want {
+			t.Errorf("Decode(%s): Mismatches = %+v, want one with Path %q", in, ms, want)
+		}
+	}
+}
+
+func TestAllowTypeMismatchSpecialKeys(t *testing.T) {
+	type Inner struct {
+		Price int `json:"unit.price"`
+	}
+	type T struct {
+		Dot     int     `json:"a.b"`
+		Space   int     `json:"with space"`
+		Unicode int     `json:"ключ"`
+		Dash    int     `json:"-,"`
+		Bracket int     `json:"x[0]"`
+		Inner   []Inner `json:"in ner"`
+		Skipped int     `json:"-"`
+	}
+	in := `{"a.b": "x", "with space": "x", "ключ": "x", "-": "x", "x[0]": "x", "in ner": [{"unit.price": "x"}], "Skipped": "x"}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	v := T{Dot: 1, Space: 1, Unicode: 1, Dash: 1, Bracket: 1, Skipped: 1}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := (T{Inner: []Inner{{}}, Skipped: 1}); !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	var fields, paths []string
+	for _, m := range dec.Mismatches() {
+		fields = append(fields, m.Field)
+		paths = append(paths, m.Path)
+	}
+	wantFields := []string{"a.b", "with space", "ключ", "-", "x[0]", "in ner.unit.price"}
+	if !slices.Equal(fields, wantFields) {
+		t.Errorf("Mismatches fields:\n\tgot:  %q\n\twant: %q", fields, wantFields)
+	}
+	wantPaths := []string{`["a.b"]`, "with space", "ключ", "-", `["x[0]"]`, `in ner[0]["unit.price"]`}
+	if !slices.Equal(paths, wantPaths) {
+		t.Errorf("Mismatches paths:\n\tgot:  %q\n\twant: %q", paths, wantPaths)
+	}
+	for _, path := range wantFields {
+		if !dec.WasMismatched(path) {
+			t.Errorf("WasMismatched(%q) = false, want true", path)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	type Node struct {
+		V     int   `json:"v"`
+		Child *Node `json:"c"`
+	}
+	nested := func(n int) string {
+		return strings.Repeat(`{"v": 1, "c": `, n) + "null" + strings.Repeat("}", n)
+	}
+
+	dec := NewDecoder(strings.NewReader(nested(1000)))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetMaxDepth(3)
+	var root Node
+	if err := dec.Decode(&root); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if root.Child == nil || root.Child.Child == nil || root.Child.Child.V != 1 || root.Child.Child.Child != nil {
+		t.Errorf("Decode: want three levels, got %+v", root)
+	}
+	ms := dec.Mismatches()
+	if len(ms) != 1 || ms[0].Field != "c.c.c" || ms[0].Value != "object" || ms[0].Type != reflect.TypeFor[*Node]() {
+		t.Errorf("Mismatches = %+v, want one for c.c.c", ms)
+	}
+
+	// Within the limit, nothing changes.
+	dec = NewDecoder(strings.NewReader(nested(3)))
+	dec.AllowTypeMismatch()
+	dec.SetMaxDepth(4)
+	if err := dec.Decode(new(Node)); err != nil || dec.HadMismatch() {
+		t.Errorf("Decode within max depth: error %v, mismatch %v", err, dec.HadMismatch())
+	}
+
+	// Values decoded into interfaces are cut too.
+	in := strings.Repeat("[", 1000) + strings.Repeat("]", 1000)
+	dec = NewDecoder(strings.NewReader(`{"a": ` + in + `, "b": {"c": {}}}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetMaxDepth(2)
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := map[string]any{"a": []any{nil}, "b": map[string]any{"c": nil}}; !reflect.DeepEqual(m, want) {
+		t.Errorf("Decode:\n\tgot:  %#v\n\twant: %#v", m, want)
+	}
+	if n := len(dec.Mismatches()); n != 2 {
+		t.Errorf("Decode: %d mismatches, want 2", n)
+	}
+
+	// The mismatch limit applies to values decoded into interfaces too.
+	for _, v := range []any{new(any), new([][]any)} {
+		dec = NewDecoder(strings.NewReader(`[[[1]], [[2]]]`))
+		dec.AllowTypeMismatch()
+		dec.SetMaxDepth(2)
+		dec.SetMaxMismatches(0)
+		var lerr *TooManyMismatchesError
+		if err := dec.Decode(v); !errors.As(err, &lerr) {
+			t.Errorf("Decode into %T: error %v, want TooManyMismatchesError", v, err)
+		}
+	}
+
+	// Without tolerance, it is an error.
+	dec = NewDecoder(strings.NewReader(nested(5)))
+	dec.SetMaxDepth(2)
+	var derr *MaxDepthError
+	err := dec.Decode(new(Node))
+	if !errors.As(err, &derr) || derr.Depth != 2 || derr.Value != "object" || derr.Offset != int64(len(`{"v": 1, "c": {"v": 1, "c": `)) {
+		t.Fatalf("Decode error: %v, want MaxDepthError", err)
+	}
+	if want := "json: object at offset 28 exceeds the max depth of 2"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestArraysAsIndexMaps(t *testing.T) {
+	type Value struct {
+		N int `json:"n"`
+	}
+	type T struct {
+		Values map[int]Value  `json:"values"`
+		Small  map[uint8]bool `json:"small"`
+		Names  map[string]int `json:"names"`
+		After  int            `json:"after"`
+	}
+	in := `{"values": [{"n": 1}, {"n": "x"}, {"n": 3}], "small": [true, false], "names": [1], "after": 5}`
+
+	// By default, an array is a tolerated mismatch for any map.
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	v := T{Values: map[int]Value{7: {N: 7}}}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := (T{After: 5}); !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", v, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"values", "small", "names"}; !slices.Equal(paths, want) {
+		t.Errorf("Mismatches paths:\n\tgot:  %q\n\twant: %q", paths, want)
+	}
+
+	// With the option, the elements are keyed by index in integer maps.
+	for _, merge := range []bool{false, true} {
+		dec = NewDecoder(strings.NewReader(in))
+		dec.AllowTypeMismatch()
+		dec.RecordMismatches()
+		dec.SetArraysAsIndexMaps(true)
+		dec.SetMergeMode(merge)
+		v = T{Values: map[int]Value{1: {N: 8}, 7: {N: 7}}}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode in merge mode %v: error %v", merge, err)
+		}
+		want := T{
+			Values: map[int]Value{0: {N: 1}, 1: {}, 2: {N: 3}, 7: {N: 7}},
+			Small:  map[uint8]bool{0: true, 1: false},
+			After:  5,
+		}
+		if merge {
+			want.Values[1] = Value{N: 8}
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Decode in merge mode %v:\n\tgot:  %+v\n\twant: %+v", merge, v, want)
+		}
+		paths = nil
+		for _, m := range dec.Mismatches() {
+			paths = append(paths, m.Path)
+		}
+		if want := []string{"values[1].n", "names"}; !slices.Equal(paths, want) {
+			t.Errorf("Decode in merge mode %v: Mismatches paths:\n\tgot:  %q\n\twant: %q", merge, paths, want)
+		}
+	}
+
+	// An index that overflows the key type is an error.
+	dec = NewDecoder(strings.NewReader(`[` + strings.Repeat(`0, `, 128) + `1]`))
+	dec.AllowTypeMismatch()
+	dec.SetArraysAsIndexMaps(true)
+	var m map[int8]int
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(&m); !errors.As(err, &ute) || ute.Value != "number 128" || len(m) != 128 {
+		t.Errorf("Decode with overflowing index: %d entries, error %v, want 128 and UnmarshalTypeError", len(m), err)
+	}
+}
+
+func TestMismatchesUnder(t *testing.T) {
+	type Item struct {
+		Price int   `json:"price"`
+		Tags  []int `json:"tags"`
+	}
+	type T struct {
+		Items     []Item `json:"items"`
+		ItemsSeen int    `json:"itemsSeen"`
+		Name      string `json:"name"`
+	}
+	in := `{"items": [{"price": "x"}, {"price": 2, "tags": [1, "a"]}, "bad"], "itemsSeen": "x", "name": 5}`
+
+	tests := []struct {
+		CaseName
+		format func([]PathSegment) string
+		prefix string
+		want   []string
+	}{
+		{Name(""), nil, "", []string{"items[0].price", "items[1].tags[1]", "items[2]", "itemsSeen", "name"}},
+		{Name(""), nil, "items", []string{"items[0].price", "items[1].tags[1]", "items[2]"}},
+		{Name(""), nil, "items[1]", []string{"items[1].tags[1]"}},
+		{Name(""), nil, "items[]", []string{"items[0].price", "items[1].tags[1]", "items[2]"}},
+		{Name(""), nil, "items[].price", []string{"items[0].price"}},
+		{Name(""), nil, "items[].tags[]", []string{"items[1].tags[1]"}},
+		{Name(""), nil, "items[3]", nil},
+		{Name(""), nil, "item", nil},
+		{Name(""), nil, "name", []string{"name"}},
+		{Name(""), func(segments []PathSegment) string {
+			var b strings.Builder
+			for _, s := range segments {
+				if s.Array {
+					fmt.Fprintf(&b, "/%d", s.Index)
+				} else {
+					b.WriteString("/" + s.Key)
+				}
+			}
+			return b.String()
+		}, "/items/1", []string{"/items/1/tags/1"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(in))
+			dec.AllowTypeMismatch()
+			dec.RecordMismatches()
+			dec.SetPathFormatter(tc.format)
+			if err := dec.Decode(new(T)); err != nil {
+				t.Fatalf("%s: Decode error: %v", tc.Where, err)
+			}
+			var got []string
+			for _, m := range dec.MismatchesUnder(tc.prefix) {
+				got = append(got, m.Path)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("%s: MismatchesUnder(%q):\n\tgot:  %q\n\twant: %q", tc.Where, tc.prefix, got, tc.want)
+			}
+		})
+	}
+}
+
+// panickyUnmarshaler is an Unmarshaler with a bug: it indexes its input
+// without checking its length, so it panics on short values.
+type panickyUnmarshaler struct {
+	First, Last byte
+}
+
+func (p *panickyUnmarshaler) UnmarshalJSON(b []byte) error {
+	p.First = b[1]
+	p.Last = b[3]
+	return nil
+}
+
+// panickyText is a TextUnmarshaler that panics on empty text.
+type panickyText string
+
+func (p *panickyText) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		panic("panickyText: empty text")
+	}
+	*p = panickyText(b)
+	return nil
+}
+
+func TestRecoverUnmarshalPanics(t *testing.T) {
+	type T struct {
+		Value panickyUnmarshaler  `json:"value"`
+		Ptr   *panickyUnmarshaler `json:"ptr"`
+		Texts []panickyText       `json:"texts"`
+		Name  string              `json:"name"`
+	}
+	in := `{"value": [1], "ptr": "abcd", "texts": ["a", "", "c"], "name": "n"}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetRecoverUnmarshalPanics(true)
+	got := T{Value: panickyUnmarshaler{'x', 'y'}}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{
+		Ptr:   &panickyUnmarshaler{'a', 'c'},
+		Texts: []panickyText{"a", "", "c"},
+		Name:  "n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		if m.Panic == nil {
+			t.Errorf("mismatch at %s: Panic is nil", m.Path)
+		}
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"value", "texts[1]"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+	if ms := dec.Mismatches(); len(ms) == 2 && ms[1].Panic != "panickyText: empty text" {
+		t.Errorf("Panic = %v, want %q", ms[1].Panic, "panickyText: empty text")
+	}
+
+	// Without AllowTypeMismatch, the panic is returned as an error.
+	dec = NewDecoder(strings.NewReader(in))
+	dec.SetRecoverUnmarshalPanics(true)
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(new(T)); !errors.As(err, &ute) || ute.Field != "value" {
+		t.Errorf("Decode error: %v, want UnmarshalTypeError for field value", err)
+	}
+
+	// Off by default: the panic is not recovered.
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Decode did not panic")
+		}
+	}()
+	dec = NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.Decode(new(T))
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestWriteMismatchReport(t *testing.T) {
+	type Item struct {
+		Price float64  `json:"price"`
+		Tags  []string `json:"tags"`
+	}
+	type T struct {
+		Name  string    `json:"name"`
+		Items []Item    `json:"items"`
+		When  time.Time `json:"when"`
+	}
+	in := `{"Name": 1, "items": [{"price": "9.99"}, {"tags": ["a", {}]}], "when": null, "items": 2}`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetReportCaseFolding(true)
+	if err := dec.Decode(new(T)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMismatchReport(&buf, dec.Mismatches()); err != nil {
+		t.Fatalf("WriteMismatchReport error: %v", err)
+	}
+	const golden = "testdata/mismatch_report.golden"
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("WriteMismatchReport:\n\tgot:  %s\n\twant: %s", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteMismatchReport(&buf, nil); err != nil {
+		t.Fatalf("WriteMismatchReport error: %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("WriteMismatchReport(nil) = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestLenientConfigNewDecoder(t *testing.T) {
+	type T struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	cfg := LenientConfig{
+		AllowTypeMismatch: true,
+		RecordMismatches:  true,
+		StringCoercion:    true,
+		TimeLayouts:       []string{time.DateOnly},
+		AllowComments:     true,
+	}
+
+	// The config is shared by the goroutines, each with its own Decoder.
+	// Run with -race to check that they do not share memory.
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in := fmt.Sprintf(`{"id": "%d", /* c */ "tags": ["a", %d]} {"id": true}`, i, i)
+			dec := cfg.NewDecoder(strings.NewReader(in))
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				errs[i] = err
+				return
+			}
+			if v.ID != i || len(dec.Mismatches()) != 1 || dec.Mismatches()[0].Path != "tags[1]" {
+				errs[i] = fmt.Errorf("first record: got %+v with mismatches %+v", v, dec.Mismatches())
+				return
+			}
+			if err := dec.Decode(&v); err != nil {
+				errs[i] = err
+				return
+			}
+			if v.ID != 0 || len(dec.Mismatches()) != 1 {
+				errs[i] = fmt.Errorf("second record: got %+v with mismatches %+v", v, dec.Mismatches())
+			}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("worker %d: %v", i, err)
+		}
+	}
+
+	// The decoders do not share memory with the config.
+	dec := cfg.NewDecoder(nil)
+	cfg.TimeLayouts[0] = time.RFC1123
+	if got := dec.Config().TimeLayouts; !slices.Equal(got, []string{time.DateOnly}) {
+		t.Errorf("Decoder TimeLayouts = %q after changing the config, want %q", got, []string{time.DateOnly})
+	}
+}
+
+// Box, Pair and Page are generic types, whose instances are decoded with
+// their type arguments.
+type Box[T any] struct {
+	Val T `json:"val"`
+}
+
+type Pair[K comparable, V any] struct {
+	Key    K       `json:"key"`
+	Values map[K]V `json:"values"`
+}
+
+type Page[T any] struct {
+	Items []T      `json:"items"`
+	Next  *Page[T] `json:"next"`
+}
+
+func TestAllowTypeMismatchGeneric(t *testing.T) {
+	t.Run("Box", func(t *testing.T) {
+		got, report, err := DecodeValid[[]Box[int]]([]byte(`[{"val": 1}, {"val": "2"}, {"val": [3]}]`))
+		if err != nil {
+			t.Fatalf("DecodeValid error: %v", err)
+		}
+		if want := []Box[int]{{1}, {0}, {0}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeValid:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		if len(report) != 2 {
+			t.Fatalf("DecodeValid: %d mismatches, want 2", len(report))
+		}
+		for _, m := range report {
+			if m.Struct != "Box[int]" || m.Type != reflect.TypeFor[int]() {
+				t.Errorf("mismatch at %s: Struct = %q, Type = %v, want Box[int] and int", m.Path, m.Struct, m.Type)
+			}
+		}
+	})
+	t.Run("Pair", func(t *testing.T) {
+		got, report, err := DecodeValid[Pair[string, Box[float64]]]([]byte(
+			`{"key": 5, "values": {"a": {"val": 1.5}, "b": {"val": true}}}`))
+		if err != nil {
+			t.Fatalf("DecodeValid error: %v", err)
+		}
+		want := Pair[string, Box[float64]]{Values: map[string]Box[float64]{"a": {1.5}, "b": {0}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeValid:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		var fields []string
+		for _, m := range report {
+			fields = append(fields, m.Struct+"."+m.Field)
+		}
+		if want := []string{"Pair[string,github.com/otaxhu/type-mismatch-encoding/encoding/json.Box[float64]].key", "Box[float64].values.val"}; !slices.Equal(fields, want) {
+			t.Errorf("mismatched fields = %q, want %q", fields, want)
+		}
+	})
+	t.Run("Page", func(t *testing.T) {
+		got, report, err := DecodeValid[Page[Box[bool]]]([]byte(
+			`{"items": [{"val": true}], "next": {"items": [{"val": "yes"}, 1], "next": "none"}}`))
+		if err != nil {
+			t.Fatalf("DecodeValid error: %v", err)
+		}
+		want := Page[Box[bool]]{
+			Items: []Box[bool]{{true}},
+			Next:  &Page[Box[bool]]{Items: []Box[bool]{{false}, {false}}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeValid:\n\tgot:  %+v\n\twant: %+v", got, want)
+		}
+		var paths []string
+		for _, m := range report {
+			paths = append(paths, m.Path)
+		}
+		if want := []string{"next.items[0].val", "next.items[1]", "next.next"}; !slices.Equal(paths, want) {
+			t.Errorf("mismatched paths = %q, want %q", paths, want)
+		}
+	})
+}
+
+func TestTypeMismatchError(t *testing.T) {
+	type Item struct {
+		Price int `json:"price"`
+	}
+	type T struct {
+		Name  string             `json:"name"`
+		Items []Item             `json:"items"`
+		Value panickyUnmarshaler `json:"value"`
+	}
+	dec := NewDecoder(strings.NewReader(`{"Name": "a", "items": [{}, {}, {"price": "9"}], "value": 1}`))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.SetReportCaseFolding(true)
+	dec.SetRecoverUnmarshalPanics(true)
+	if err := dec.Decode(new(T)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	var got []string
+	for _, m := range dec.Mismatches() {
+		got = append(got, m.Error())
+	}
+	want := []string{
+		`json: case folding at name: object key "Name" matched a field of type string`,
+		"json: type mismatch at items[2].price: expected int, got string",
+		"json: type mismatch at value: expected json.panickyUnmarshaler, got number (panic: runtime error: index out of range [1] with length 1)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Error:\n\tgot:  %q\n\twant: %q", got, want)
+	}
+
+	// A mismatch of the whole value has no path.
+	_, report, _ := DecodeValid[int]([]byte(`[]`))
+	if len(report) != 1 {
+		t.Fatalf("DecodeValid: %d mismatches, want 1", len(report))
+	}
+	var err error = report[0]
+	if got, want := err.Error(), "json: type mismatch: expected int, got array"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	var m TypeMismatch
+	if !errors.As(fmt.Errorf("record 3: %w", err), &m) || m.Path != "" {
+		t.Errorf("errors.As did not find the wrapped TypeMismatch")
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	type T struct {
+		ID    int                   `json:"id"`
+		Tags  []string              `json:"tags"`
+		Extra map[string]RawMessage `json:",extra"`
+	}
+	in := `{"id": "x", "color": "red", "tags": ["a", 1], "size": {"w": 2, "h": [3]}, "n": null}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	dec.DisallowUnknownFields()
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{
+		Tags: []string{"a", ""},
+		Extra: map[string]RawMessage{
+			"color": RawMessage(`"red"`),
+			"size":  RawMessage(`{"w": 2, "h": [3]}`),
+			"n":     RawMessage(`null`),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"id", "tags[1]"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+
+	// The extra members are marshaled after the fields, in key order, but
+	// for the keys of the fields.
+	got.Extra["id"] = RawMessage(`"shadowed"`)
+	b, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if want := `{"id":0,"tags":["a",""],"color":"red","n":null,"size":{"w":2,"h":[3]}}`; string(b) != want {
+		t.Errorf("Marshal:\n\tgot:  %s\n\twant: %s", b, want)
+	}
+
+	// An embedded struct can hold the extra field.
+	type Base struct {
+		Extra map[string]RawMessage `json:",extra"`
+	}
+	type U struct {
+		*Base
+		Name string `json:"name"`
+	}
+	var u U
+	if err := Unmarshal([]byte(`{"name": "a", "more": true}`), &u); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if u.Name != "a" || u.Base == nil || string(u.Extra["more"]) != "true" {
+		t.Errorf("Unmarshal: got %+v with base %+v", u, u.Base)
+	}
+	if b, err := Marshal(U{Name: "b"}); err != nil || string(b) != `{"name":"b"}` {
+		t.Errorf("Marshal = %s, %v, want {\"name\":\"b\"}", b, err)
+	}
+}
+
+func TestSetAllowBOM(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+	}
+	const bom = "\xef\xbb\xbf"
+	in := bom + `{"a": 1} {"a": 2}`
+	readers := []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{"Reader", func() io.Reader { return strings.NewReader(in) }},
+		{"OneByteReader", func() io.Reader { return iotest.OneByteReader(strings.NewReader(in)) }},
+	}
+	for _, tc := range readers {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(tc.r())
+			dec.SetAllowBOM(true)
+			for _, want := range []int{1, 2} {
+				var v T
+				if err := dec.Decode(&v); err != nil {
+					t.Fatalf("Decode error: %v", err)
+				}
+				if v.A != want {
+					t.Errorf("Decode: A = %d, want %d", v.A, want)
+				}
+			}
+			if got, want := dec.InputOffset(), int64(len(in)); got != want {
+				t.Errorf("InputOffset = %d, want %d", got, want)
+			}
+
+			dec = NewDecoder(tc.r())
+			dec.SetAllowBOM(true)
+			if tok, err := dec.Token(); err != nil || tok != Delim('{') {
+				t.Errorf("Token = %v, %v, want {", tok, err)
+			}
+		})
+	}
+
+	// A BOM is a syntax error without the option, and anywhere but at the
+	// start of the input.
+	var v T
+	var serr *SyntaxError
+	for _, in := range []string{bom + `{"a": 1}`, `{"a": 1} ` + bom + `{"a": 2}`, "\xef\xbb"} {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.SetAllowBOM(!strings.HasPrefix(in, bom))
+		err := dec.Decode(&v)
+		if err == nil {
+			err = dec.Decode(&v)
+		}
+		if !errors.As(err, &serr) {
+			t.Errorf("Decode(%q) error: %v, want SyntaxError", in, err)
+		}
+	}
+
+	// A document that is only a BOM is empty.
+	dec := NewDecoder(strings.NewReader(bom))
+	dec.SetAllowBOM(true)
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Errorf("Decode error: %v, want io.EOF", err)
+	}
+}
+
+func TestDiffStrictTolerant(t *testing.T) {
+	type T struct {
+		ID    int      `json:"id"`
+		Count int      `json:"count,string"`
+		Big   *big.Int `json:"big"`
+		Tags  []string `json:"tags"`
+	}
+	tests := []struct {
+		CaseName
+		in    string
+		paths []string
+		err   bool
+	}{
+		{CaseName: Name("Strict"), in: `{"id": 1, "count": "2", "big": 3, "tags": ["a"]}`},
+		{CaseName: Name("TypeMismatch"), in: `{"id": "1", "count": "2", "tags": ["a", 1]}`, paths: []string{"id", "tags[1]"}},
+		{CaseName: Name("StringOption"), in: `{"id": 1, "count": 2}`, paths: []string{"count"}},
+		{CaseName: Name("Unmarshaler"), in: `{"big": "x"}`, paths: []string{"big"}},
+		{CaseName: Name("SyntaxError"), in: `{"id": 1`, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			var got T
+			report, err := DiffStrictTolerant([]byte(tc.in), &got)
+			if (err != nil) != tc.err {
+				t.Fatalf("%s: DiffStrictTolerant error: %v, want error: %v", tc.Where, err, tc.err)
+			}
+			if tc.err {
+				return
+			}
+			var paths []string
+			for _, m := range report {
+				paths = append(paths, m.Path)
+			}
+			if !slices.Equal(paths, tc.paths) {
+				t.Errorf("%s: repaired paths = %q, want %q", tc.Where, paths, tc.paths)
+			}
+
+			// The diff is the tolerant report, and v the tolerant result.
+			want, wantReport, err := DecodeValid[T]([]byte(tc.in))
+			if err != nil {
+				t.Fatalf("%s: DecodeValid error: %v", tc.Where, err)
+			}
+			if !reflect.DeepEqual(report, wantReport) {
+				t.Errorf("%s: DiffStrictTolerant:\n\tgot:  %+v\n\twant: %+v", tc.Where, report, wantReport)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: decoded value:\n\tgot:  %+v\n\twant: %+v", tc.Where, got, want)
+			}
+		})
+	}
+
+	var ierr *InvalidUnmarshalError
+	if _, err := DiffStrictTolerant([]byte(`{}`), T{}); !errors.As(err, &ierr) {
+		t.Errorf("DiffStrictTolerant(non-pointer) error: %v, want InvalidUnmarshalError", err)
+	}
+}
+
+// Shape is an interface with implementations registered with RegisterImpl:
+// Circle by value and Rect by pointer.
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Type   string  `json:"type"`
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Rect struct {
+	W, H int
+}
+
+func (r *Rect) Area() float64 { return float64(r.W * r.H) }
+
+func TestRegisterImpl(t *testing.T) {
+	type Drawing struct {
+		Main   Shape   `json:"main"`
+		Shapes []Shape `json:"shapes"`
+		Meta   any     `json:"meta"`
+	}
+	in := `{
+		"main": {"type": "circle", "radius": "big"},
+		"shapes": [
+			{"type": "circle", "radius": 2},
+			{"W": 2, "type": "rect", "H": [3]},
+			{"type": "hexagon", "side": 1},
+			{"radius": 1},
+			{"type": 1, "W": 4, "H": 5}
+		],
+		"meta": {"type": "circle", "radius": 1}
+	}`
+	newDecoder := func() *Decoder {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.RegisterImpl(reflect.TypeFor[Shape](), "circle", reflect.TypeFor[Circle]())
+		dec.RegisterImpl(reflect.TypeFor[Shape](), "rect", reflect.TypeFor[Rect]())
+		dec.RegisterImpl(reflect.TypeFor[Shape](), "1", reflect.TypeFor[*Rect]())
+		return dec
+	}
+
+	dec := newDecoder()
+	dec.AllowTypeMismatch()
+	dec.RecordMismatches()
+	var got Drawing
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := Drawing{
+		Main: Circle{Type: "circle"},
+		Shapes: []Shape{
+			Circle{Type: "circle", Radius: 2},
+			&Rect{W: 2},
+			nil,
+			nil,
+			&Rect{W: 4, H: 5},
+		},
+		// Only the implementations of Shape are registered.
+		Meta: map[string]any{"type": "circle", "radius": 1.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"main.radius", "shapes[1].H", "shapes[2]", "shapes[3]"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+
+	// Without AllowTypeMismatch, the mismatches are errors.
+	dec = newDecoder()
+	var ute *UnmarshalTypeError
+	if err := dec.Decode(new(Drawing)); !errors.As(err, &ute) || ute.Field != "main.radius" {
+		t.Errorf("Decode error: %v, want UnmarshalTypeError for field main.radius", err)
+	}
+
+	// The discriminator key can be changed.
+	dec = NewDecoder(strings.NewReader(`{"kind": "rect", "W": 1, "H": 2}`))
+	dec.RegisterImpl(reflect.TypeFor[Shape](), "rect", reflect.TypeFor[Rect]())
+	dec.SetDiscriminatorKey("kind")
+	var s Shape
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if !reflect.DeepEqual(s, &Rect{W: 1, H: 2}) {
+		t.Errorf("Decode: got %#v, want &Rect{W: 1, H: 2}", s)
+	}
+
+	// Invalid registrations panic.
+	for _, tc := range []struct {
+		name            string
+		iface, concrete reflect.Type
+	}{
+		{"NotInterface", reflect.TypeFor[Circle](), reflect.TypeFor[Circle]()},
+		{"NotImplemented", reflect.TypeFor[Shape](), reflect.TypeFor[string]()},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RegisterImpl did not panic", tc.name)
+				}
+			}()
+			NewDecoder(nil).RegisterImpl(tc.iface, "x", tc.concrete)
+		}()
+	}
+}
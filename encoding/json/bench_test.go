@@ -286,6 +286,99 @@ func BenchmarkCodeDecoder(b *testing.B) {
 	b.SetBytes(int64(len(codeJSON)))
 }
 
+func BenchmarkCodeDecoderAllowTypeMismatch(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	for _, allow := range []bool{false, true} {
+		b.Run(fmt.Sprintf("AllowTypeMismatch=%v", allow), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				var buf bytes.Buffer
+				dec := NewDecoder(&buf)
+				if allow {
+					dec.AllowTypeMismatch()
+				}
+				var r codeResponse
+				for pb.Next() {
+					buf.Write(codeJSON)
+					// hide EOF
+					buf.WriteByte('\n')
+					buf.WriteByte('\n')
+					buf.WriteByte('\n')
+					if err := dec.Decode(&r); err != nil {
+						b.Fatalf("Decode error: %v", err)
+					}
+				}
+			})
+			b.SetBytes(int64(len(codeJSON)))
+		})
+	}
+}
+
+func BenchmarkDecoderSmallObjectsAllowTypeMismatch(b *testing.B) {
+	type T struct {
+		String  string  `json:"string"`
+		Int     int     `json:"int"`
+		Float64 float64 `json:"float64"`
+		Bool    bool    `json:"bool"`
+	}
+	data := []byte(`{"string":"test","int":123,"float64":123.123,"bool":true}` + "\n")
+	for _, allow := range []bool{false, true} {
+		b.Run(fmt.Sprintf("AllowTypeMismatch=%v", allow), func(b *testing.B) {
+			b.ReportAllocs()
+			r := bytes.NewReader(data)
+			dec := NewDecoder(r)
+			if allow {
+				dec.AllowTypeMismatch()
+			}
+			var v T
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Reset(data)
+				dec.Reset(r)
+				if err := dec.Decode(&v); err != nil {
+					b.Fatalf("Decode error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecoderMismatchAllocs(b *testing.B) {
+	type T struct {
+		Int     int     `json:"int"`
+		Float64 float64 `json:"float64"`
+		Bool    bool    `json:"bool"`
+	}
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{"Valid", []byte(`{"int":123,"float64":123.123,"bool":true}`)},
+		{"Mismatched", []byte(`{"int":123.123,"float64":"123.123","bool":123}`)},
+	}
+	for _, in := range inputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportAllocs()
+			r := bytes.NewReader(in.data)
+			dec := NewDecoder(r)
+			dec.AllowTypeMismatch()
+			var v T
+			for i := 0; i < b.N; i++ {
+				r.Reset(in.data)
+				dec.Reset(r)
+				if err := dec.Decode(&v); err != nil {
+					b.Fatalf("Decode error: %v", err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkUnicodeDecoder(b *testing.B) {
 	b.ReportAllocs()
 	j := []byte(`"\uD83D\uDE01"`)
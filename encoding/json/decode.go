@@ -8,12 +8,14 @@
 package json
 
 import (
+	"bytes"
 	"encoding"
 	"encoding/base64"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
@@ -45,7 +47,8 @@ import (
 // keys to the keys used by [Marshal] (either the struct field name or its tag),
 // preferring an exact match but also accepting a case-insensitive match. By
 // default, object keys which don't have a corresponding struct field are
-// ignored (see [Decoder.DisallowUnknownFields] for an alternative).
+// ignored (see [Decoder.DisallowUnknownFields] for an alternative), or stored in
+// the field with the "extra" option, if any, as described in [Marshal].
 //
 // To unmarshal JSON into an interface value,
 // Unmarshal stores one of these in the interface value:
@@ -175,6 +178,7 @@ func (d *decodeState) unmarshal(v any) error {
 	}
 
 	d.scan.reset()
+	d.lenient = d.anyLenient()
 	d.scanWhile(scanSkipSpace)
 	// We decode rv not rv.Elem because the Unmarshaler interface
 	// test must be applied at the top level of the value.
@@ -217,7 +221,47 @@ type decodeState struct {
 	savedError            error
 	useNumber             bool
 	disallowUnknownFields bool
+	lenient               bool // whether any option below is set, see anyLenient
 	allowTypeMismatch     bool
+	coerceStrings         bool
+	numbersAsStrings      bool
+	decimalComma          bool
+	recoverPanics         bool
+	durationStrings       bool
+	intFromFloat          bool
+	reportCaseFolding     bool
+	emptyCollections      bool
+	timeLayouts           []string
+	overflowMismatch      bool
+	mergeMode             bool
+	indexMaps             bool
+	duplicateKeyBestMatch bool
+	toleratePaths         []string
+	tolerateUnmarshaler   bool          // see embeddedUnmarshaler
+	arrays                []arrayIndex  // arrays being decoded, innermost last
+	segments              []PathSegment // reused by path
+	pathFormatter         func([]PathSegment) string
+	inputOffset           int64 // offset of data in the input of a Decoder
+	valueStart            int   // offset in data of the value being stored
+	coercions             map[reflect.Type]func(RawMessage) (any, error)
+	impls                 map[reflect.Type]map[string]reflect.Type // see RegisterImpl
+	discriminatorKey      string
+	recordMismatches      bool
+	mismatchAsError       bool
+	mismatchHandler       func(TypeMismatch)
+	mismatchResolver      func(string, reflect.Type, RawMessage) (any, bool)
+	logger                Logger
+	maxMismatches         int   // negative means unlimited
+	structBudget          int   // mismatches tolerated per struct, negative means unlimited
+	maxDepth              int   // zero means unlimited
+	structMismatches      []int // mismatches of each struct being decoded, innermost last
+	mismatches            []TypeMismatch
+	cumulative            bool     // set by Decoder.SetCumulativeMismatches
+	record                int      // index of the record being decoded, if cumulative
+	recordPaths           []string // Path of each of mismatches prefixed with record, if cumulative
+	mismatchCount         int
+	mismatchLimitErr      error
+	unmarshalPanic        any // see callUnmarshaler
 }
 
 // readIndex returns the position of the last byte read.
@@ -234,6 +278,14 @@ func (d *decodeState) init(data []byte) *decodeState {
 	d.data = data
 	d.off = 0
 	d.savedError = nil
+	d.mismatches = nil
+	d.recordPaths = nil
+	d.mismatchCount = 0
+	d.mismatchLimitErr = nil
+	d.arrays = d.arrays[:0]
+	d.structMismatches = d.structMismatches[:0]
+	d.inputOffset = 0
+	d.valueStart = 0
 	if d.errorContext != nil {
 		d.errorContext.Struct = nil
 		// Reuse the allocated space for the FieldStack slice.
@@ -250,6 +302,125 @@ func (d *decodeState) saveError(err error) {
 	}
 }
 
+// saveTypeError saves an [UnmarshalTypeError] for the JSON value described by
+// value that is not appropriate for a Go value of type t. If literal is not
+// nil, it is appended to the description, as in "number -5". raw is the
+// whole JSON value, reported with tolerated mismatches.
+//
+// If the decoder allows type mismatches, the mismatch is passed to the
+// mismatch handler and recorded instead, if either of them was requested.
+// Once more mismatches than allowed are found, d.mismatchLimitErr is set and
+// the callers return it as soon as possible to stop decoding.
+func (d *decodeState) saveTypeError(value string, literal, raw []byte, t reflect.Type, offset int) {
+	if !d.tolerates() {
+		if literal != nil {
+			value += " " + string(literal)
+		}
+		d.saveError(&UnmarshalTypeError{Value: value, Type: t, Offset: int64(offset)})
+		return
+	}
+	d.mismatchCount++
+	if n := len(d.structMismatches); n > 0 {
+		d.structMismatches[n-1]++
+	}
+	record := d.recordMismatches || d.mismatchAsError
+	if d.mismatchHandler == nil && d.logger == nil && !record && !d.exceedsMismatchLimit() {
+		return
+	}
+	if literal != nil {
+		value += " " + string(literal)
+	}
+	// Copy raw, so that the report does not keep the input buffer alive.
+	m := TypeMismatch{
+		Value:       value,
+		Raw:         bytes.Clone(raw),
+		Type:        t,
+		Offset:      int64(offset),
+		InputOffset: d.inputOffset + int64(d.valueStart),
+		ActualKind:  kindOf(raw),
+		Panic:       d.unmarshalPanic,
+	}
+	if d.errorContext != nil && (d.errorContext.Struct != nil || len(d.errorContext.FieldStack) > 0) {
+		m.Struct = d.errorContext.Struct.Name()
+		m.Field = strings.Join(d.errorContext.FieldStack, ".")
+	}
+	m.Path = d.path()
+	if d.mismatchHandler != nil {
+		d.mismatchHandler(m)
+	}
+	if d.logger != nil {
+		d.logMismatch(m)
+	}
+	if record {
+		d.mismatches = append(d.mismatches, m)
+		if d.cumulative {
+			d.recordPaths = append(d.recordPaths, d.recordPath())
+		}
+	}
+	if d.exceedsMismatchLimit() && d.mismatchLimitErr == nil {
+		d.mismatchLimitErr = &TooManyMismatchesError{
+			Limit:      d.maxMismatches,
+			Count:      d.mismatchCount,
+			Mismatches: d.mismatches,
+		}
+	}
+}
+
+// saveOverflowError saves an [UnmarshalTypeError] for the JSON number literal
+// that overflows v. If the decoder treats overflows as type mismatches, it is
+// handled as one instead.
+func (d *decodeState) saveOverflowError(literal []byte, v reflect.Value, offset int) {
+	if d.overflowMismatch {
+		d.saveValueTypeError("number", literal, literal, v, offset)
+		return
+	}
+	d.saveError(&UnmarshalTypeError{Value: "number " + string(literal), Type: v.Type(), Offset: int64(offset)})
+}
+
+// exceedsMismatchLimit reports whether more type mismatches than allowed by
+// the decoder have been tolerated.
+func (d *decodeState) exceedsMismatchLimit() bool {
+	return d.maxMismatches >= 0 && d.mismatchCount > d.maxMismatches
+}
+
+// saveValueTypeError is like saveTypeError, for the JSON value raw that is not
+// appropriate for v. If a coercion function is registered for the type of v,
+// and it succeeds, its result is stored in v and no error is saved.
+// Otherwise, if the decoder allows type mismatches, v is set to the value of
+// the mismatch resolver, or to its zero value unless the decoder is in merge
+// mode. A v that no JSON value can be decoded
+// into, such as a channel or a function, is never a type mismatch, and an
+// [UnsupportedTypeError] is saved for it instead.
+func (d *decodeState) saveValueTypeError(value string, literal, raw []byte, v reflect.Value, offset int) {
+	// Saving the error counts it against the budget of the struct, so
+	// whether it is tolerated is decided before.
+	tolerant := d.tolerates()
+	if tolerant && !decodableKind(v.Kind()) {
+		d.saveError(&UnsupportedTypeError{v.Type()})
+		return
+	}
+	if d.coerce(raw, v) {
+		return
+	}
+	d.saveTypeError(value, literal, raw, v.Type(), offset)
+	if !tolerant || !v.CanSet() || d.resolve(raw, v) {
+		return
+	}
+	if !d.mergeMode {
+		d.setZero(v)
+	}
+}
+
+// decodableKind reports whether a value of kind k can hold a decoded JSON
+// value. The kinds it rejects are the ones that [Marshal] does not support.
+func decodableKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
 // addErrorContext returns a new error enhanced with information from d.errorContext
 func (d *decodeState) addErrorContext(err error) error {
 	if d.errorContext != nil && (d.errorContext.Struct != nil || len(d.errorContext.FieldStack) > 0) {
@@ -329,6 +500,10 @@ Switch:
 			}
 		}
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '-': // number
+		if i < len(data) && data[i] == 'I' { // -Infinity
+			i += len("Infinity")
+			break
+		}
 		for ; i < len(data); i++ {
 			switch data[i] {
 			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
@@ -343,6 +518,10 @@ Switch:
 		i += len("alse")
 	case 'n': // null
 		i += len("ull")
+	case 'N': // NaN
+		i += len("aN")
+	case 'I': // Infinity
+		i += len("nfinity")
 	}
 	if i < len(data) {
 		d.opcode = stateEndValue(&d.scan, data[i])
@@ -361,7 +540,11 @@ func (d *decodeState) value(v reflect.Value) error {
 		panic(phasePanicMsg)
 
 	case scanBeginArray:
-		if v.IsValid() {
+		if v.IsValid() && d.tooDeep() {
+			if err := d.depthMismatch("array", v); err != nil {
+				return err
+			}
+		} else if v.IsValid() {
 			if err := d.array(v); err != nil {
 				return err
 			}
@@ -371,7 +554,11 @@ func (d *decodeState) value(v reflect.Value) error {
 		d.scanNext()
 
 	case scanBeginObject:
-		if v.IsValid() {
+		if v.IsValid() && d.tooDeep() {
+			if err := d.depthMismatch("object", v); err != nil {
+				return err
+			}
+		} else if v.IsValid() {
 			if err := d.object(v); err != nil {
 				return err
 			}
@@ -386,6 +573,7 @@ func (d *decodeState) value(v reflect.Value) error {
 		d.rescanLiteral()
 
 		if v.IsValid() {
+			d.valueStart = start
 			if err := d.literalStore(d.data[start:d.readIndex()], v, false); err != nil {
 				return err
 			}
@@ -500,20 +688,45 @@ func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnm
 // array consumes an array from d.data[d.off-1:], decoding into v.
 // The first byte of the array ('[') has been read already.
 func (d *decodeState) array(v reflect.Value) error {
+	var nilPtr reflect.Value // allocated by indirect, see below
+	if d.lenient && v.Kind() == reflect.Pointer && v.IsNil() && v.CanSet() {
+		nilPtr = v
+	}
+
 	// Check for unmarshaler.
 	u, ut, pv := indirect(v, false)
+	if u != nil && d.lenient && d.nilEmbeddedUnmarshaler(u) {
+		u, pv = nil, reflect.ValueOf(u).Elem()
+	}
 	if u != nil {
-		start := d.readIndex()
+		off, start := d.off, d.readIndex()
 		d.skip()
-		return u.UnmarshalJSON(d.data[start:d.off])
+		if pv, ok := d.tolerantParse(u); ok {
+			d.valueStart = start
+			return d.unmarshalParsed(pv, "array", d.data[start:d.off], nil, off)
+		}
+		d.valueStart = start
+		_, err := d.callUnmarshaler(u, reflect.Value{}, "array", d.data[start:d.off], nil, off)
+		return err
 	}
 	if ut != nil {
+		if pv, ok := d.tolerantParse(ut); ok {
+			off, start := d.off, d.readIndex()
+			d.skip()
+			d.valueStart = start
+			d.saveValueTypeError("array", nil, d.data[start:d.off], pv, off)
+			return d.mismatchLimitErr
+		}
 		d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(d.off)})
 		d.skip()
 		return nil
 	}
 	v = pv
 
+	if d.indexMaps && v.Kind() == reflect.Map && isIntKind(v.Type().Key().Kind()) {
+		return d.indexMap(v)
+	}
+
 	// Check type of target.
 	switch v.Kind() {
 	case reflect.Interface:
@@ -526,15 +739,26 @@ func (d *decodeState) array(v reflect.Value) error {
 		// Otherwise it's invalid.
 		fallthrough
 	default:
-		if !d.allowTypeMismatch {
-			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(d.off)})
-		}
+		off, start := d.off, d.readIndex()
 		d.skip()
-		return nil
+		d.valueStart = start
+		tolerant := d.tolerates()
+		d.saveValueTypeError("array", nil, d.data[start:d.off], v, off)
+		// As in literalStore, a nil pointer to the mismatched struct is left
+		// nil, unless a coercion or resolver stored a value in it.
+		if nilPtr.IsValid() && v.Kind() == reflect.Struct && v.IsZero() && tolerant {
+			nilPtr.SetZero()
+		}
+		return d.mismatchLimitErr
 	case reflect.Array, reflect.Slice:
 		break
 	}
 
+	// Array positions are only needed for the paths of reports.
+	track := d.allowTypeMismatch || d.reportCaseFolding
+	if track {
+		d.arrays = append(d.arrays, arrayIndex{depth: d.fieldDepth()})
+	}
 	i := 0
 	for {
 		// Look ahead for ] - can only happen on first iteration.
@@ -542,6 +766,9 @@ func (d *decodeState) array(v reflect.Value) error {
 		if d.opcode == scanEndArray {
 			break
 		}
+		if track {
+			d.arrays[len(d.arrays)-1].index = i
+		}
 
 		// Expand slice length, growing the slice if necessary.
 		if v.Kind() == reflect.Slice {
@@ -578,6 +805,10 @@ func (d *decodeState) array(v reflect.Value) error {
 		}
 	}
 
+	if track {
+		d.arrays = d.arrays[:len(d.arrays)-1]
+	}
+
 	if i < v.Len() {
 		if v.Kind() == reflect.Array {
 			for ; i < v.Len(); i++ {
@@ -595,18 +826,35 @@ func (d *decodeState) array(v reflect.Value) error {
 
 var nullLiteral = []byte("null")
 var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+var unmarshalerType = reflect.TypeFor[Unmarshaler]()
 
 // object consumes an object from d.data[d.off-1:], decoding into v.
 // The first byte ('{') of the object has been read already.
 func (d *decodeState) object(v reflect.Value) error {
 	// Check for unmarshaler.
 	u, ut, pv := indirect(v, false)
+	if u != nil && d.lenient && d.nilEmbeddedUnmarshaler(u) {
+		u, pv = nil, reflect.ValueOf(u).Elem()
+	}
 	if u != nil {
-		start := d.readIndex()
+		off, start := d.off, d.readIndex()
 		d.skip()
-		return u.UnmarshalJSON(d.data[start:d.off])
+		if pv, ok := d.tolerantParse(u); ok {
+			d.valueStart = start
+			return d.unmarshalParsed(pv, "object", d.data[start:d.off], nil, off)
+		}
+		d.valueStart = start
+		_, err := d.callUnmarshaler(u, reflect.Value{}, "object", d.data[start:d.off], nil, off)
+		return err
 	}
 	if ut != nil {
+		if pv, ok := d.tolerantParse(ut); ok {
+			off, start := d.off, d.readIndex()
+			d.skip()
+			d.valueStart = start
+			d.saveValueTypeError("object", nil, d.data[start:d.off], pv, off)
+			return d.mismatchLimitErr
+		}
 		d.saveError(&UnmarshalTypeError{Value: "object", Type: v.Type(), Offset: int64(d.off)})
 		d.skip()
 		return nil
@@ -614,6 +862,13 @@ func (d *decodeState) object(v reflect.Value) error {
 	v = pv
 	t := v.Type()
 
+	// Decoding into an interface with registered implementations?
+	if v.Kind() == reflect.Interface && d.impls[t] != nil {
+		if ok, err := d.objectImpl(v); ok {
+			return err
+		}
+	}
+
 	// Decoding into nil interface? Switch to non-reflect code.
 	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
 		oi := d.objectInterface()
@@ -649,14 +904,21 @@ func (d *decodeState) object(v reflect.Value) error {
 		fields = cachedTypeFields(t)
 		// ok
 	default:
-		if !d.allowTypeMismatch {
-			d.saveError(&UnmarshalTypeError{Value: "object", Type: t, Offset: int64(d.off)})
-		}
+		off, start := d.off, d.readIndex()
 		d.skip()
-		return nil
+		d.valueStart = start
+		d.saveValueTypeError("object", nil, d.data[start:d.off], v, off)
+		return d.mismatchLimitErr
+	}
+
+	// Mismatches are counted per struct only for the budget.
+	budget := d.lenient && v.Kind() == reflect.Struct && d.structBudget >= 0
+	if budget {
+		d.structMismatches = append(d.structMismatches, 0)
 	}
 
 	var mapElem reflect.Value
+	var decoded map[*field]bool // struct fields decoded without mismatches
 	var origErrorContext errorContext
 	if d.errorContext != nil {
 		origErrorContext = *d.errorContext
@@ -684,7 +946,9 @@ func (d *decodeState) object(v reflect.Value) error {
 
 		// Figure out field corresponding to key.
 		var subv reflect.Value
+		var sf *field     // the struct field, if any
 		destring := false // whether the value is wrapped in a string to be decoded first
+		extra := false    // whether the value goes to the field with the "extra" option
 
 		if v.Kind() == reflect.Map {
 			elemType := t.Elem()
@@ -696,10 +960,13 @@ func (d *decodeState) object(v reflect.Value) error {
 			subv = mapElem
 		} else {
 			f := fields.byExactName[string(key)]
+			folded := false
 			if f == nil {
 				f = fields.byFoldedName[string(foldName(key))]
+				folded = f != nil
 			}
 			if f != nil {
+				sf = f
 				subv = v
 				destring = f.quoted
 				for _, i := range f.index {
@@ -729,11 +996,23 @@ func (d *decodeState) object(v reflect.Value) error {
 				}
 				d.errorContext.FieldStack = append(d.errorContext.FieldStack, f.name)
 				d.errorContext.Struct = t
+				if folded && d.reportCaseFolding {
+					d.saveCaseFolding(item, f.typ, start)
+				}
+			} else if fields.extra != nil {
+				extra = true
 			} else if d.disallowUnknownFields {
 				d.saveError(fmt.Errorf("json: unknown field %q", key))
 			}
 		}
 
+		// With duplicate key best match, the first value of a field that was
+		// decoded without type mismatches wins, and the following are skipped.
+		if decoded != nil && sf != nil && decoded[sf] {
+			subv = reflect.Value{}
+			destring = false
+		}
+
 		// Read : before value.
 		if d.opcode == scanSkipSpace {
 			d.scanWhile(scanSkipSpace)
@@ -743,7 +1022,10 @@ func (d *decodeState) object(v reflect.Value) error {
 		}
 		d.scanWhile(scanSkipSpace)
 
+		mismatchCount := d.mismatchCount
+		valueStart := d.readIndex()
 		if destring {
+			d.valueStart = d.readIndex()
 			switch qv := d.valueQuoted().(type) {
 			case nil:
 				if err := d.literalStore(nullLiteral, subv, false); err != nil {
@@ -754,12 +1036,25 @@ func (d *decodeState) object(v reflect.Value) error {
 					return err
 				}
 			default:
-				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", subv.Type()))
+				if !d.quotedMismatch(subv) {
+					d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", subv.Type()))
+				}
 			}
-		} else {
-			if err := d.value(subv); err != nil {
+		} else if d.lenient && d.embeddedUnmarshaler(t, sf, subv) {
+			if err := d.embeddedValue(subv); err != nil {
 				return err
 			}
+		} else if err := d.value(subv); err != nil {
+			return err
+		}
+		if extra {
+			d.storeExtra(v, fields.extra, string(key), d.data[valueStart:d.readIndex()])
+		}
+		if d.duplicateKeyBestMatch && sf != nil && d.mismatchCount == mismatchCount {
+			if decoded == nil {
+				decoded = make(map[*field]bool)
+			}
+			decoded[sf] = true
 		}
 
 		// Write value back to map;
@@ -769,6 +1064,7 @@ func (d *decodeState) object(v reflect.Value) error {
 			var kv reflect.Value
 			if reflect.PointerTo(kt).Implements(textUnmarshalerType) {
 				kv = reflect.New(kt)
+				d.valueStart = start
 				if err := d.literalStore(item, kv, true); err != nil {
 					return err
 				}
@@ -788,10 +1084,8 @@ func (d *decodeState) object(v reflect.Value) error {
 					if err != nil {
 						// got a float64, we report the error only if it doesn't allows type
 						// mismatch
-						if d.allowTypeMismatch {
-							break
-						}
-						d.saveError(&UnmarshalTypeError{Value: "number " + s, Type: kt, Offset: int64(start + 1)})
+						d.valueStart = start
+						d.saveTypeError("number", key, item, kt, start+1)
 						break
 					}
 					kv = reflect.New(kt).Elem()
@@ -806,10 +1100,8 @@ func (d *decodeState) object(v reflect.Value) error {
 					if err != nil {
 						// got a float64 or negative integer, we report the error only if it
 						// doesn't allow type mismatch
-						if d.allowTypeMismatch {
-							break
-						}
-						d.saveError(&UnmarshalTypeError{Value: "number " + s, Type: kt, Offset: int64(start + 1)})
+						d.valueStart = start
+						d.saveTypeError("number", key, item, kt, start+1)
 						break
 					}
 					kv = reflect.New(kt).Elem()
@@ -818,8 +1110,15 @@ func (d *decodeState) object(v reflect.Value) error {
 					panic("json: Unexpected key type") // should never occur
 				}
 			}
-			if kv.IsValid() {
+			if d.mergeMode && d.mismatchCount != mismatchCount {
+				// In merge mode, a mismatched element keeps the current entry.
+				if d.mismatchLimitErr != nil {
+					return d.mismatchLimitErr
+				}
+			} else if kv.IsValid() {
 				v.SetMapIndex(kv, subv)
+			} else if d.mismatchLimitErr != nil {
+				return d.mismatchLimitErr
 			}
 		}
 
@@ -841,6 +1140,10 @@ func (d *decodeState) object(v reflect.Value) error {
 			panic(phasePanicMsg)
 		}
 	}
+
+	if budget {
+		d.structMismatches = d.structMismatches[:len(d.structMismatches)-1]
+	}
 	return nil
 }
 
@@ -859,6 +1162,24 @@ func (d *decodeState) convertNumber(s string) (any, error) {
 
 var numberType = reflect.TypeFor[Number]()
 
+var durationType = reflect.TypeFor[time.Duration]()
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// literalKind returns the kind of the JSON literal item, as reported in an
+// [UnmarshalTypeError].
+func literalKind(item []byte) string {
+	switch item[0] {
+	case 'n':
+		return "null"
+	case 't', 'f':
+		return "bool"
+	case '"':
+		return "string"
+	}
+	return "number"
+}
+
 // literalStore decodes a literal stored in item into v.
 //
 // fromQuoted indicates whether this literal came from unwrapping a
@@ -868,26 +1189,39 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 	// Check for unmarshaler.
 	if len(item) == 0 {
 		// Empty string given.
-		d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
-		return nil
+		if !d.quotedMismatch(v) {
+			d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+		}
+		return d.mismatchLimitErr
 	}
 	isNull := item[0] == 'n' // null
+	var nilPtr reflect.Value // allocated by indirect, see below
+	if d.lenient && !isNull && v.Kind() == reflect.Pointer && v.IsNil() && v.CanSet() {
+		nilPtr = v
+	}
 	u, ut, pv := indirect(v, isNull)
+	if u != nil && d.lenient && d.nilEmbeddedUnmarshaler(u) {
+		u, pv = nil, reflect.ValueOf(u).Elem()
+	}
 	if u != nil {
-		return u.UnmarshalJSON(item)
+		if pv, ok := d.tolerantParse(u); ok && !isNull {
+			return d.unmarshalParsed(pv, literalKind(item), item, nil, d.readIndex())
+		}
+		_, err := d.callUnmarshaler(u, reflect.Value{}, literalKind(item), item, nil, d.readIndex())
+		return err
 	}
 	if ut != nil {
 		if item[0] != '"' {
 			if fromQuoted {
-				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
-				return nil
+				if !d.quotedMismatch(v) {
+					d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+				}
+				return d.mismatchLimitErr
 			}
-			val := "number"
-			switch item[0] {
-			case 'n':
-				val = "null"
-			case 't', 'f':
-				val = "bool"
+			val := literalKind(item)
+			if pv, ok := d.tolerantParse(ut); ok && !isNull {
+				d.saveValueTypeError(val, nil, item, pv, d.readIndex())
+				return d.mismatchLimitErr
 			}
 			d.saveError(&UnmarshalTypeError{Value: val, Type: v.Type(), Offset: int64(d.readIndex())})
 			return nil
@@ -895,21 +1229,32 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		s, ok := unquoteBytes(item)
 		if !ok {
 			if fromQuoted {
+				if d.quotedMismatch(v) {
+					return d.mismatchLimitErr
+				}
 				return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type())
 			}
 			panic(phasePanicMsg)
 		}
-		return ut.UnmarshalText(s)
+		if pv, ok := d.tolerantParse(ut); ok {
+			return d.unmarshalParsed(pv, "string", item, s, d.readIndex())
+		}
+		_, err := d.callUnmarshaler(ut, reflect.Value{}, "string", item, s, d.readIndex())
+		return err
 	}
 
 	v = pv
+	// Decided before any mismatch below counts against the struct budget.
+	tolerant := nilPtr.IsValid() && d.tolerates()
 
 	switch c := item[0]; c {
 	case 'n': // null
 		// The main parser checks that only true and false can reach here,
 		// but if this was a quoted string input, it could be anything.
 		if fromQuoted && string(item) != "null" {
-			d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+			if !d.quotedMismatch(v) {
+				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+			}
 			break
 		}
 		switch v.Kind() {
@@ -922,28 +1267,27 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		// The main parser checks that only true and false can reach here,
 		// but if this was a quoted string input, it could be anything.
 		if fromQuoted && string(item) != "true" && string(item) != "false" {
-			d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+			if !d.quotedMismatch(v) {
+				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
+			}
 			break
 		}
 		switch v.Kind() {
 		default:
 			if fromQuoted {
-				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
-			} else {
-				if d.allowTypeMismatch {
-					break // ignore type mismatch
+				if !d.quotedMismatch(v) {
+					d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
 				}
-				d.saveError(&UnmarshalTypeError{Value: "bool", Type: v.Type(), Offset: int64(d.readIndex())})
+			} else {
+				d.saveValueTypeError("bool", nil, item, v, d.readIndex())
 			}
 		case reflect.Bool:
 			v.SetBool(value)
 		case reflect.Interface:
 			if v.NumMethod() == 0 {
 				v.Set(reflect.ValueOf(value))
-			} else if d.allowTypeMismatch {
-				break // ignore type mismatch
 			} else {
-				d.saveError(&UnmarshalTypeError{Value: "bool", Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("bool", nil, item, v, d.readIndex())
 			}
 		}
 
@@ -951,22 +1295,39 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		s, ok := unquoteBytes(item)
 		if !ok {
 			if fromQuoted {
+				if d.quotedMismatch(v) {
+					return d.mismatchLimitErr
+				}
 				return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type())
 			}
 			panic(phasePanicMsg)
 		}
 		switch v.Kind() {
 		default:
-			if d.allowTypeMismatch {
-				break // ignore type mismatch
+			if d.durationStrings && v.Type() == durationType {
+				if n, err := time.ParseDuration(string(s)); err == nil {
+					v.SetInt(int64(n))
+					break
+				}
+			}
+			if d.decimalComma && !fromQuoted && isNumberKind(v.Kind()) {
+				n, ok := fromDecimalComma(s)
+				if !ok {
+					d.saveValueTypeError("string", nil, item, v, d.readIndex())
+					break
+				}
+				s = n
+			}
+			if d.coerceStrings && !fromQuoted && coerceString(s, v) {
+				break
 			}
-			d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(d.readIndex())})
+			if d.numbersAsStrings && !fromQuoted && coerceNumberString(s, v) {
+				break
+			}
+			d.saveValueTypeError("string", nil, item, v, d.readIndex())
 		case reflect.Slice:
 			if v.Type().Elem().Kind() != reflect.Uint8 {
-				if d.allowTypeMismatch {
-					break // ignore type mismatch
-				}
-				d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("string", nil, item, v, d.readIndex())
 				break
 			}
 			b := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
@@ -985,20 +1346,24 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		case reflect.Interface:
 			if v.NumMethod() == 0 {
 				v.Set(reflect.ValueOf(string(s)))
-			} else if d.allowTypeMismatch {
-				break // ignore type mismatch
 			} else {
-				d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("string", nil, item, v, d.readIndex())
 			}
 		}
 
 	default: // number
-		if c != '-' && (c < '0' || c > '9') {
+		if c != '-' && (c < '0' || c > '9') && !d.nonFinite(c) {
 			if fromQuoted {
+				if d.quotedMismatch(v) {
+					return d.mismatchLimitErr
+				}
 				return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type())
 			}
 			panic(phasePanicMsg)
 		}
+		if d.scan.allowLeadingZeros {
+			item = trimLeadingZeros(item)
+		}
 		switch v.Kind() {
 		default:
 			if v.Kind() == reflect.String && v.Type() == numberType {
@@ -1008,12 +1373,12 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 				break
 			}
 			if fromQuoted {
+				if d.quotedMismatch(v) {
+					return d.mismatchLimitErr
+				}
 				return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type())
 			}
-			if d.allowTypeMismatch {
-				break // ignore type mismatch
-			}
-			d.saveError(&UnmarshalTypeError{Value: "number", Type: v.Type(), Offset: int64(d.readIndex())})
+			d.saveValueTypeError("number", nil, item, v, d.readIndex())
 		case reflect.Interface:
 			n, err := d.convertNumber(string(item))
 			if err != nil {
@@ -1021,43 +1386,50 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 				break
 			}
 			if v.NumMethod() != 0 {
-				if d.allowTypeMismatch {
-					break // ignore type mismatch
-				}
-				d.saveError(&UnmarshalTypeError{Value: "number", Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("number", nil, item, v, d.readIndex())
 				break
 			}
 			v.Set(reflect.ValueOf(n))
 
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if d.lenient && !isInteger(item, true) {
+				if d.intFromFloat && integralFloat(item, v) {
+					break
+				}
+				// not an integer, no need to allocate a strconv error to know it
+				d.saveValueTypeError("number", item, item, v, d.readIndex())
+				break
+			}
 			n, err := strconv.ParseInt(string(item), 10, 64)
 			if v.OverflowInt(n) {
-				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveOverflowError(item, v, d.readIndex())
 				break
 			}
 			if err != nil {
 				// got a float64, we report the error only if it doesn't allow type mismatch
-				if d.allowTypeMismatch {
-					break
-				}
-				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("number", item, item, v, d.readIndex())
 				break
 			}
 			v.SetInt(n)
 
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if d.lenient && !isInteger(item, false) {
+				if d.intFromFloat && integralFloat(item, v) {
+					break
+				}
+				// not an unsigned integer, no need to allocate a strconv error to know it
+				d.saveValueTypeError("number", item, item, v, d.readIndex())
+				break
+			}
 			n, err := strconv.ParseUint(string(item), 10, 64)
 			if v.OverflowUint(n) {
-				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveOverflowError(item, v, d.readIndex())
 				break
 			}
 			if err != nil {
 				// got a float64 or negative integer, we report the error whether it doesn't
 				// allow type mismatch
-				if d.allowTypeMismatch {
-					break
-				}
-				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveValueTypeError("number", item, item, v, d.readIndex())
 				break
 			}
 			v.SetUint(n)
@@ -1065,13 +1437,80 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		case reflect.Float32, reflect.Float64:
 			n, err := strconv.ParseFloat(string(item), v.Type().Bits())
 			if err != nil || v.OverflowFloat(n) {
-				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				d.saveOverflowError(item, v, d.readIndex())
 				break
 			}
 			v.SetFloat(n)
 		}
 	}
-	return nil
+	// A literal never matches a struct, so a nil pointer to the mismatched
+	// struct is left nil rather than pointing to its zero value, unless a
+	// coercion or resolver stored a value in it.
+	if tolerant && v.Kind() == reflect.Struct && v.IsZero() {
+		nilPtr.SetZero()
+	}
+	return d.mismatchLimitErr
+}
+
+// coerceString parses s as the bool or number that v holds, and stores it
+// in v. It reports whether s was parsed; v is left untouched otherwise.
+func coerceString(s []byte, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(string(s))
+		if err != nil {
+			return false
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isInteger(s, true) {
+			return false
+		}
+		n, err := strconv.ParseInt(string(s), 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return false
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !isInteger(s, false) {
+			return false
+		}
+		n, err := strconv.ParseUint(string(s), 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return false
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		// Only accept JSON number syntax, so that "NaN" or "Inf" are not coerced.
+		if !isValidNumber(string(s)) {
+			return false
+		}
+		n, err := strconv.ParseFloat(string(s), v.Type().Bits())
+		if err != nil || v.OverflowFloat(n) {
+			return false
+		}
+		v.SetFloat(n)
+	default:
+		return false
+	}
+	return true
+}
+
+// isInteger reports whether item is a decimal integer literal, optionally
+// preceded by a minus sign if signed is true.
+func isInteger(item []byte, signed bool) bool {
+	if signed && len(item) > 0 && item[0] == '-' {
+		item = item[1:]
+	}
+	if len(item) == 0 {
+		return false
+	}
+	for _, c := range item {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // The xxxInterface routines build up a value to be stored
@@ -1083,11 +1522,20 @@ func (d *decodeState) valueInterface() (val any) {
 	switch d.opcode {
 	default:
 		panic(phasePanicMsg)
-	case scanBeginArray:
-		val = d.arrayInterface()
-		d.scanNext()
-	case scanBeginObject:
-		val = d.objectInterface()
+	case scanBeginArray, scanBeginObject:
+		if d.tooDeep() {
+			value := "array"
+			if d.opcode == scanBeginObject {
+				value = "object"
+			}
+			if err := d.depthMismatch(value, reflect.ValueOf(&val).Elem()); err != nil {
+				d.saveError(err)
+			}
+		} else if d.opcode == scanBeginArray {
+			val = d.arrayInterface()
+		} else {
+			val = d.objectInterface()
+		}
 		d.scanNext()
 	case scanBeginLiteral:
 		val = d.literalInterface()
@@ -1195,9 +1643,12 @@ func (d *decodeState) literalInterface() any {
 		return s
 
 	default: // number
-		if c != '-' && (c < '0' || c > '9') {
+		if c != '-' && (c < '0' || c > '9') && !d.nonFinite(c) {
 			panic(phasePanicMsg)
 		}
+		if d.scan.allowLeadingZeros {
+			item = trimLeadingZeros(item)
+		}
 		n, err := d.convertNumber(string(item))
 		if err != nil {
 			d.saveError(err)
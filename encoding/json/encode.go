@@ -106,6 +106,14 @@ import (
 //
 //	Int64String int64 `json:",string"`
 //
+// The "extra" option, on a field of type map[string]RawMessage, signals that
+// the field holds the object keys that have no corresponding struct field.
+// Its entries are marshaled in key order as members of the object, after the
+// other fields, except for the keys of the other fields, and [Unmarshal]
+// stores there the members that it would otherwise ignore:
+//
+//	Extra map[string]json.RawMessage `json:",extra"`
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, and ASCII punctuation except quotation
 // marks, backslash, and comma.
@@ -261,6 +269,13 @@ type encodeState struct {
 	// reasonable amount of nested pointers deep.
 	ptrLevel uint
 	ptrSeen  map[any]struct{}
+
+	// omitPaths and nullPaths hold the field paths set with
+	// Encoder.OmitPaths and Encoder.NullPaths, and path the names of the
+	// struct fields being encoded, only when one of them is not empty.
+	omitPaths map[string]bool
+	nullPaths map[string]bool
+	path      []string
 }
 
 const startDetectingCyclesAfter = 1000
@@ -275,6 +290,9 @@ func newEncodeState() *encodeState {
 			panic("ptrEncoder.encode should have emptied ptrSeen via defers")
 		}
 		e.ptrLevel = 0
+		e.omitPaths = nil
+		e.nullPaths = nil
+		e.path = e.path[:0]
 		return e
 	}
 	return &encodeState{ptrSeen: make(map[any]struct{})}
@@ -372,6 +390,8 @@ func typeEncoder(t reflect.Type) encoderFunc {
 var (
 	marshalerType     = reflect.TypeFor[Marshaler]()
 	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	rawMessageType    = reflect.TypeFor[RawMessage]()
+	extraType         = reflect.TypeFor[map[string]RawMessage]()
 )
 
 // newTypeEncoder constructs an encoderFunc for a type.
@@ -681,6 +701,7 @@ type structFields struct {
 	list         []field
 	byExactName  map[string]*field
 	byFoldedName map[string]*field
+	extra        []int // index of the field with the "extra" option, if any
 }
 
 func (se structEncoder) encode(e *encodeState, v reflect.Value, opts encOpts) {
@@ -701,7 +722,21 @@ FieldLoop:
 			fv = fv.Field(i)
 		}
 
-		if f.omitEmpty && isEmptyValue(fv) {
+		trackPath := len(e.omitPaths) > 0 || len(e.nullPaths) > 0
+		null := false
+		if trackPath {
+			e.path = append(e.path, f.name)
+			path := strings.Join(e.path, ".")
+			if e.omitPaths[path] {
+				e.path = e.path[:len(e.path)-1]
+				continue
+			}
+			null = e.nullPaths[path]
+		}
+		if !null && f.omitEmpty && isEmptyValue(fv) {
+			if trackPath {
+				e.path = e.path[:len(e.path)-1]
+			}
 			continue
 		}
 		e.WriteByte(next)
@@ -711,8 +746,18 @@ FieldLoop:
 		} else {
 			e.WriteString(f.nameNonEsc)
 		}
-		opts.quoted = f.quoted
-		f.encoder(e, fv, opts)
+		if null {
+			e.WriteString("null")
+		} else {
+			opts.quoted = f.quoted
+			f.encoder(e, fv, opts)
+		}
+		if trackPath {
+			e.path = e.path[:len(e.path)-1]
+		}
+	}
+	if se.fields.extra != nil {
+		next = se.encodeExtra(e, v, next, opts)
 	}
 	if next == '{' {
 		e.WriteString("{}")
@@ -721,6 +766,37 @@ FieldLoop:
 	}
 }
 
+// encodeExtra writes the entries of the field of v with the "extra" option as
+// members of the object, after next, and returns the byte to write before the
+// next member.
+func (se structEncoder) encodeExtra(e *encodeState, v reflect.Value, next byte, opts encOpts) byte {
+	for _, i := range se.fields.extra {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return next
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	enc := typeEncoder(rawMessageType)
+	for _, k := range keys {
+		if se.fields.byExactName[k.String()] != nil {
+			continue
+		}
+		e.WriteByte(next)
+		next = ','
+		e.Write(appendString(e.AvailableBuffer(), k.String(), opts.escapeHTML))
+		e.WriteByte(':')
+		enc(e, v.MapIndex(k), opts)
+	}
+	return next
+}
+
 func newStructEncoder(t reflect.Type) encoderFunc {
 	se := structEncoder{fields: cachedTypeFields(t)}
 	return se.encode
@@ -1080,6 +1156,9 @@ func typeFields(t reflect.Type) structFields {
 	// Fields found.
 	var fields []field
 
+	// Index of the field with the "extra" option.
+	var extra []int
+
 	// Buffer to run appendHTMLEscape on field names.
 	var nameEscBuf []byte
 
@@ -1142,6 +1221,15 @@ func typeFields(t reflect.Type) structFields {
 					}
 				}
 
+				// The first field with the "extra" option, at the least
+				// nested level, holds the unknown keys.
+				if opts.Contains("extra") && sf.Type == extraType {
+					if extra == nil {
+						extra = index
+					}
+					continue
+				}
+
 				// Record found field and index sequence.
 				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
 					tagged := name != ""
@@ -1248,7 +1336,7 @@ func typeFields(t reflect.Type) structFields {
 			foldedNameIndex[string(foldName(field.nameBytes))] = &fields[i]
 		}
 	}
-	return structFields{fields, exactNameIndex, foldedNameIndex}
+	return structFields{fields, exactNameIndex, foldedNameIndex, extra}
 }
 
 // dominantField looks through the fields, all of which are known to
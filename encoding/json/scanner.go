@@ -79,6 +79,28 @@ type scanner struct {
 	// Error that happened, if any.
 	err error
 
+	// Whether // and /* */ comments are skipped as spaces, see
+	// Decoder.SetAllowComments, the state to return to after a comment, and
+	// whether the scanner is in the middle of one.
+	allowComments bool
+	afterComment  func(*scanner, byte) int
+	inComment     bool
+
+	// Whether the last element of an array or object may be followed by a
+	// comma, see Decoder.SetAllowTrailingCommas.
+	allowTrailingCommas bool
+
+	// Whether a number may have leading zeros, as in 0123, see
+	// Decoder.SetAllowLeadingZeros.
+	allowLeadingZeros bool
+
+	// Whether the literals NaN, Infinity and -Infinity are numbers, see
+	// Decoder.SetAllowNonFiniteFloats, and the one being read with the
+	// number of its bytes read so far.
+	allowNonFinite bool
+	nonFinite      string
+	nonFiniteRead  int
+
 	// total bytes consumed, updated by decoder.Decode (and deliberately
 	// not set to zero by scan.reset)
 	bytes int64
@@ -94,6 +116,10 @@ func newScanner() *scanner {
 	scan := scannerPool.Get().(*scanner)
 	// scan.reset by design doesn't set bytes to zero
 	scan.bytes = 0
+	scan.allowComments = false
+	scan.allowTrailingCommas = false
+	scan.allowLeadingZeros = false
+	scan.allowNonFinite = false
 	scan.reset()
 	return scan
 }
@@ -152,6 +178,8 @@ func (s *scanner) reset() {
 	s.parseState = s.parseState[0:0]
 	s.err = nil
 	s.endTop = false
+	s.afterComment = nil
+	s.inComment = false
 }
 
 // eof tells the scanner that the end of input has been reached.
@@ -208,6 +236,9 @@ func stateBeginValueOrEmpty(s *scanner, c byte) int {
 	if c == ']' {
 		return stateEndValue(s, c)
 	}
+	if c == '/' && s.allowComments {
+		return s.beginComment(stateBeginValueOrEmpty)
+	}
 	return stateBeginValue(s, c)
 }
 
@@ -246,6 +277,17 @@ func stateBeginValue(s *scanner, c byte) int {
 		s.step = state1
 		return scanBeginLiteral
 	}
+	// The lenient syntax is checked last, so that it costs nothing for valid
+	// input.
+	if (c == 'N' || c == 'I') && s.allowNonFinite { // beginning of NaN or Infinity
+		return s.beginNonFinite(c, scanBeginLiteral)
+	}
+	if c == '/' && s.allowComments {
+		return s.beginComment(stateBeginValue)
+	}
+	if c == ']' && s.allowTrailingCommas && s.inArray() { // after `[1,`
+		return stateEndValue(s, c)
+	}
 	return s.error(c, "looking for beginning of value")
 }
 
@@ -259,6 +301,9 @@ func stateBeginStringOrEmpty(s *scanner, c byte) int {
 		s.parseState[n-1] = parseObjectValue
 		return stateEndValue(s, c)
 	}
+	if c == '/' && s.allowComments {
+		return s.beginComment(stateBeginStringOrEmpty)
+	}
 	return stateBeginString(s, c)
 }
 
@@ -271,6 +316,12 @@ func stateBeginString(s *scanner, c byte) int {
 		s.step = stateInString
 		return scanBeginLiteral
 	}
+	if c == '/' && s.allowComments {
+		return s.beginComment(stateBeginString)
+	}
+	if c == '}' && s.allowTrailingCommas { // after `{"a":1,`
+		return stateBeginStringOrEmpty(s, c)
+	}
 	return s.error(c, "looking for beginning of object key string")
 }
 
@@ -296,6 +347,9 @@ func stateEndValue(s *scanner, c byte) int {
 			s.step = stateBeginValue
 			return scanObjectKey
 		}
+		if c == '/' && s.allowComments {
+			return s.beginComment(stateEndValue)
+		}
 		return s.error(c, "after object key")
 	case parseObjectValue:
 		if c == ',' {
@@ -307,6 +361,9 @@ func stateEndValue(s *scanner, c byte) int {
 			s.popParseState()
 			return scanEndObject
 		}
+		if c == '/' && s.allowComments {
+			return s.beginComment(stateEndValue)
+		}
 		return s.error(c, "after object key:value pair")
 	case parseArrayValue:
 		if c == ',' {
@@ -317,15 +374,28 @@ func stateEndValue(s *scanner, c byte) int {
 			s.popParseState()
 			return scanEndArray
 		}
+		if c == '/' && s.allowComments {
+			return s.beginComment(stateEndValue)
+		}
 		return s.error(c, "after array element")
 	}
 	return s.error(c, "")
 }
 
+// inArray reports whether the innermost value being scanned is an array.
+func (s *scanner) inArray() bool {
+	n := len(s.parseState)
+	return n > 0 && s.parseState[n-1] == parseArrayValue
+}
+
 // stateEndTop is the state after finishing the top-level value,
 // such as after reading `{}` or `[1,2,3]`.
 // Only space characters should be seen now.
 func stateEndTop(s *scanner, c byte) int {
+	if c == '/' && s.allowComments {
+		s.beginComment(stateEndTop)
+		return scanEnd
+	}
 	if !isSpace(c) {
 		// Complain about non-space byte on next call.
 		s.error(c, "after top-level value")
@@ -333,6 +403,77 @@ func stateEndTop(s *scanner, c byte) int {
 	return scanEnd
 }
 
+// beginComment is called for the `/` starting a comment, which is skipped as
+// a space before continuing with state next.
+func (s *scanner) beginComment(next func(*scanner, byte) int) int {
+	s.step = stateCommentSlash
+	s.afterComment = next
+	s.inComment = true
+	return scanSkipSpace
+}
+
+// endComment is called for the last byte of a comment.
+func (s *scanner) endComment() int {
+	s.step = s.afterComment
+	s.afterComment = nil
+	s.inComment = false
+	return scanSkipSpace
+}
+
+// stateCommentSlash is the state after reading the `/` of a comment.
+func stateCommentSlash(s *scanner, c byte) int {
+	switch c {
+	case '/':
+		s.step = stateLineComment
+		return scanSkipSpace
+	case '*':
+		s.step = stateBlockComment
+		return scanSkipSpace
+	}
+	return s.error(c, "looking for beginning of comment")
+}
+
+// stateLineComment is the state after reading `//`.
+func stateLineComment(s *scanner, c byte) int {
+	if c == '\n' {
+		return s.endComment()
+	}
+	return scanSkipSpace
+}
+
+// stateBlockComment is the state after reading `/*`.
+func stateBlockComment(s *scanner, c byte) int {
+	if c == '*' {
+		s.step = stateBlockCommentStar
+	}
+	return scanSkipSpace
+}
+
+// stateBlockCommentStar is the state after reading `/*` and then `*`.
+func stateBlockCommentStar(s *scanner, c byte) int {
+	switch c {
+	case '/':
+		return s.endComment()
+	case '*':
+	default:
+		s.step = stateBlockComment
+	}
+	return scanSkipSpace
+}
+
+// onlyComments reports whether data holds nothing but spaces and complete
+// comments, a line comment being complete at the end of data.
+func onlyComments(data []byte) bool {
+	s := scanner{allowComments: true}
+	s.reset()
+	for _, c := range data {
+		if s.step(&s, c) != scanSkipSpace {
+			return false
+		}
+	}
+	return s.step(&s, '\n') == scanSkipSpace && !s.inComment
+}
+
 // stateInString is the state after reading `"`.
 func stateInString(s *scanner, c byte) int {
 	if c == '"' {
@@ -412,6 +553,9 @@ func stateNeg(s *scanner, c byte) int {
 		s.step = state1
 		return scanContinue
 	}
+	if c == 'I' && s.allowNonFinite {
+		return s.beginNonFinite(c, scanContinue)
+	}
 	return s.error(c, "in numeric literal")
 }
 
@@ -427,6 +571,10 @@ func state1(s *scanner, c byte) int {
 
 // state0 is the state after reading `0` during a number.
 func state0(s *scanner, c byte) int {
+	if s.allowLeadingZeros && '0' <= c && c <= '9' {
+		s.step = state1
+		return scanContinue
+	}
 	if c == '.' {
 		s.step = stateDot
 		return scanContinue
@@ -581,6 +729,32 @@ func stateNul(s *scanner, c byte) int {
 	return s.error(c, "in literal null (expecting 'l')")
 }
 
+// beginNonFinite is called for the first byte c of the literal NaN or
+// Infinity, and returns op.
+func (s *scanner) beginNonFinite(c byte, op int) int {
+	s.nonFinite = "NaN"
+	if c == 'I' {
+		s.nonFinite = "Infinity"
+	}
+	s.nonFiniteRead = 1
+	s.step = stateNonFinite
+	return op
+}
+
+// stateNonFinite is the state in the middle of the literal NaN or Infinity,
+// such as after reading `Inf`.
+func stateNonFinite(s *scanner, c byte) int {
+	want := s.nonFinite[s.nonFiniteRead]
+	if c != want {
+		return s.error(c, "in literal "+s.nonFinite+" (expecting "+quoteChar(want)+")")
+	}
+	s.nonFiniteRead++
+	if s.nonFiniteRead == len(s.nonFinite) {
+		s.step = stateEndValue
+	}
+	return scanContinue
+}
+
 // stateError is the state after reaching a syntax error,
 // such as after reading `[1}` or `5.1.2`.
 func stateError(s *scanner, c byte) int {
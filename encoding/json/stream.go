@@ -6,11 +6,15 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"time"
 )
 
 // A Decoder reads and decodes JSON values from an input stream.
+// It is not safe for concurrent use: the goroutines that decode with the same
+// settings should each have a Decoder, as created by [LenientConfig.NewDecoder].
 type Decoder struct {
 	r       io.Reader
 	buf     []byte
@@ -22,6 +26,16 @@ type Decoder struct {
 
 	tokenState int
 	tokenStack []int
+
+	ctx context.Context // set during DecodeContext
+
+	structuralErrorHandler func(err error) error
+	versionField           string
+	versionPolicies        map[string]Policy
+
+	allowBOM bool           // set by SetAllowBOM
+	report   []TypeMismatch // mismatches of all the records, if cumulative
+	records  int            // number of records in report
 }
 
 // NewDecoder returns a new decoder that reads from r.
@@ -29,7 +43,10 @@ type Decoder struct {
 // The decoder introduces its own buffering and may
 // read data from r beyond the JSON values requested.
 func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r: r}
+	dec := &Decoder{r: r}
+	dec.d.maxMismatches = -1
+	dec.d.structBudget = -1
+	return dec
 }
 
 // UseNumber causes the Decoder to unmarshal a number into an interface{} as a
@@ -48,39 +65,92 @@ func (dec *Decoder) DisallowUnknownFields() { dec.d.disallowUnknownFields = true
 // AllowTypeMismatch causes the Decoder to not return an error when the
 // input contains a JSON value that does not match the type of the destination value.
 //
-// The destination value remains unmodified if the types does not match.
+// The destination value is set to its zero value if the types does not match.
+// A nil pointer to a struct is left nil when the JSON value is a string, a
+// number or a boolean, instead of pointing to a zero struct.
+// Input that cannot be parsed into a [math/big.Int], [math/big.Float] or
+// [math/big.Rat] is also handled as a type mismatch, and so is an error of a
+// pointer to an [Unmarshaler] embedded in a struct, which is set to nil, while
+// the errors of other Unmarshaler implementations are returned as they are.
+// A struct that embeds such a pointer, while it is nil, is decoded field by
+// field rather than with the UnmarshalJSON method it gets from the pointer.
+// A value of a type that cannot hold any JSON value, such as a channel or a
+// function, is not tolerated either, and makes Decode return an
+// [UnsupportedTypeError].
+// A [RawMessage], or a struct embedding one, accepts any JSON value as it is,
+// so it never mismatches.
+// An instance of a generic type, as Box[int] of
+// type Box[T any] struct{ Val T }, is decoded with its type arguments, and
+// the [TypeMismatch.Struct] of its fields includes them as
+// [reflect.Type.Name] does, as in "Box[int]".
+// The mismatches that were tolerated can be reported with
+// [Decoder.RecordMismatches] and [Decoder.SetMismatchHandler].
+//
+// The setting applies to the following calls to [Decoder.Decode], so it can be
+// changed between them, see [Decoder.DisallowTypeMismatch].
 func (dec *Decoder) AllowTypeMismatch() { dec.d.allowTypeMismatch = true }
 
+// DisallowTypeMismatch undoes [Decoder.AllowTypeMismatch], so that the
+// following calls to [Decoder.Decode] return an [UnmarshalTypeError] for a JSON
+// value that does not match the type of the destination value. This is the
+// default.
+func (dec *Decoder) DisallowTypeMismatch() { dec.d.allowTypeMismatch = false }
+
+// Reset discards any buffered data and state of the Decoder, including the
+// mismatches reported by [Decoder.Mismatches], and makes it read from r.
+// Options such as [Decoder.UseNumber], [Decoder.DisallowUnknownFields],
+// [Decoder.AllowTypeMismatch] and the mismatch handler are preserved, as is
+// the capacity of the internal buffer.
+func (dec *Decoder) Reset(r io.Reader) {
+	dec.r = r
+	dec.buf = dec.buf[:0]
+	dec.scanp = 0
+	dec.scanned = 0
+	dec.scan.reset()
+	dec.err = nil
+	dec.tokenState = tokenTopValue
+	dec.tokenStack = dec.tokenStack[:0]
+	dec.d.init(nil)
+	dec.ResetMismatches()
+}
+
 // Decode reads the next JSON-encoded value from its
 // input and stores it in the value pointed to by v.
 //
 // See the documentation for [Unmarshal] for details about
 // the conversion of JSON into a Go value.
 func (dec *Decoder) Decode(v any) error {
+	// The mismatches are those of this call, even if it fails before
+	// decoding a value, so that they are not taken for the previous ones.
+	dec.d.mismatches = nil
+	dec.d.recordPaths = nil
+	dec.d.mismatchCount = 0
+
 	if dec.err != nil {
-		return dec.err
+		return dec.structuralError(dec.err)
 	}
 
 	if err := dec.tokenPrepareForDecode(); err != nil {
-		return err
+		return dec.structuralError(err)
 	}
 
 	if !dec.tokenValueAllowed() {
-		return &SyntaxError{msg: "not at beginning of value", Offset: dec.InputOffset()}
+		return dec.structuralError(&SyntaxError{msg: "not at beginning of value", Offset: dec.InputOffset()})
 	}
 
 	// Read whole value into buffer.
 	n, err := dec.readValue()
 	if err != nil {
-		return err
+		return dec.structuralError(err)
 	}
 	dec.d.init(dec.buf[dec.scanp : dec.scanp+n])
+	dec.d.inputOffset = dec.InputOffset()
 	dec.scanp += n
 
 	// Don't save err from unmarshal into dec.err:
 	// the connection is still usable since we read a complete JSON
 	// object from it before the error happened.
-	err = dec.d.unmarshal(v)
+	err = dec.unmarshal(v)
 
 	// fixup token streaming state
 	dec.tokenValueEnd()
@@ -88,6 +158,132 @@ func (dec *Decoder) Decode(v any) error {
 	return err
 }
 
+// unmarshal stores in v the value that dec.d was initialized with, under the
+// policy of its version, and reports its mismatches as the settings of dec
+// require.
+func (dec *Decoder) unmarshal(v any) error {
+	dec.d.record = dec.records
+	allowTypeMismatch := dec.d.allowTypeMismatch
+	if p, ok := dec.versionPolicy(dec.d.data); ok {
+		dec.d.allowTypeMismatch = p == PolicyAllowTypeMismatch
+	}
+	err := dec.d.unmarshal(v)
+	dec.d.allowTypeMismatch = allowTypeMismatch
+	if err == nil && dec.d.mismatchAsError && dec.d.mismatchCount > 0 {
+		err = &MismatchError{Mismatches: typeMismatches(dec.d.mismatches)}
+	}
+	if dec.d.cumulative {
+		dec.accumulateMismatches()
+	}
+	return err
+}
+
+// DecodeContext is like [Decoder.Decode], but it stops reading the input and
+// returns ctx.Err() once ctx is done. The context is checked before every read
+// from the underlying reader, so a read that blocks is not interrupted.
+//
+// A value whose decoding was canceled can be decoded again by a later call
+// to Decode or DecodeContext, because the data read so far is kept buffered.
+func (dec *Decoder) DecodeContext(ctx context.Context, v any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dec.ctx = ctx
+	defer func() { dec.ctx = nil }()
+	return dec.Decode(v)
+}
+
+// DecodeTimeout is like [Decoder.DecodeContext] with a context that is done
+// once timeout has elapsed, which returns [context.DeadlineExceeded] if the
+// value was not read in time. It bounds the time spent on untrusted input
+// without managing a context.
+func (dec *Decoder) DecodeTimeout(timeout time.Duration, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return dec.DecodeContext(ctx, v)
+}
+
+// DecodePartial is like [Decoder.Decode], but it salvages what it can of a
+// value that ends early, as in a truncated stream, or that has a syntax
+// error. It returns the type mismatches tolerated in v, as recorded by
+// [Decoder.RecordMismatches] whether it is set or not, and the error that
+// Decode returns, which is fatalErr.
+//
+// If the value is incomplete, v is decoded from the part of it that precedes
+// the point where the input ended or became invalid: every array and object
+// that is still open there holds the elements that are complete, and the
+// incomplete one is left out, as is a top-level value that is not an array
+// or object. That part is decoded as by Decode, so an error in decoding it,
+// such as a [*TooManyMismatchesError], is joined to fatalErr. The Decoder
+// keeps returning the error of the input afterwards, as after Decode.
+// For any other error, v is left as Decode leaves it: filled but for the
+// values that did not match their type, or up to the value whose
+// [Unmarshaler] failed.
+func (dec *Decoder) DecodePartial(v any) (mismatches []TypeMismatch, fatalErr error) {
+	record := dec.d.recordMismatches
+	dec.d.recordMismatches = true
+	defer func() { dec.d.recordMismatches = record }()
+
+	prior := dec.err
+	fatalErr = dec.Decode(v)
+	if prior != nil || dec.err == nil {
+		return dec.d.mismatches, fatalErr
+	}
+	data := partialValue(dec.buf[dec.scanp:], &dec.scan)
+	if data == nil {
+		return nil, fatalErr
+	}
+	dec.d.init(data)
+	dec.d.inputOffset = dec.InputOffset()
+	if err := dec.unmarshal(v); err != nil {
+		fatalErr = errors.Join(fatalErr, err)
+	}
+	return dec.d.mismatches, fatalErr
+}
+
+// partialValue returns the longest prefix of data, the start of an array or
+// object that is incomplete, that ends after a complete element, or after
+// the opening of an array or object, followed by the bytes that close the
+// arrays and objects still open there. It scans data with the syntax that
+// scan allows, and returns nil if data is not an array or object.
+func partialValue(data []byte, scan *scanner) []byte {
+	s := scanner{
+		allowComments:       scan.allowComments,
+		allowTrailingCommas: scan.allowTrailingCommas,
+		allowLeadingZeros:   scan.allowLeadingZeros,
+		allowNonFinite:      scan.allowNonFinite,
+	}
+	s.reset()
+	cut := -1
+	var closers []byte
+	checkpoint := func(i int) {
+		cut = i
+		closers = closers[:0]
+		for j := len(s.parseState) - 1; j >= 0; j-- {
+			if s.parseState[j] == parseArrayValue {
+				closers = append(closers, ']')
+			} else {
+				closers = append(closers, '}')
+			}
+		}
+	}
+Scan:
+	for i, c := range data {
+		switch s.step(&s, c) {
+		case scanError:
+			break Scan
+		case scanBeginObject, scanBeginArray, scanEndObject, scanEndArray:
+			checkpoint(i + 1)
+		case scanObjectValue, scanArrayValue:
+			checkpoint(i) // before the comma
+		}
+	}
+	if cut < 0 {
+		return nil
+	}
+	return append(bytes.Clone(data[:cut]), closers...)
+}
+
 // Buffered returns a reader of the data remaining in the Decoder's
 // buffer. The reader is valid until the next call to [Decoder.Decode].
 func (dec *Decoder) Buffered() io.Reader {
@@ -99,13 +295,16 @@ func (dec *Decoder) Buffered() io.Reader {
 func (dec *Decoder) readValue() (int, error) {
 	dec.scan.reset()
 
-	scanp := dec.scanp
 	var err error
+	for dec.skipBOM() && err == nil {
+		err = dec.refill()
+	}
+
+	scanp := dec.scanp
 Input:
 	// help the compiler see that scanp is never negative, so it can remove
 	// some bounds checks below.
 	for scanp >= 0 {
-
 		// Look in the buffer for a new value.
 		for ; scanp < len(dec.buf); scanp++ {
 			c := dec.buf[scanp]
@@ -138,7 +337,7 @@ Input:
 				if dec.scan.step(&dec.scan, ' ') == scanEnd {
 					break Input
 				}
-				if nonSpace(dec.buf) {
+				if nonSpace(dec.buf) && !(dec.scan.allowComments && onlyComments(dec.buf)) {
 					err = io.ErrUnexpectedEOF
 				}
 			}
@@ -146,6 +345,14 @@ Input:
 			return 0, err
 		}
 
+		if dec.ctx != nil {
+			if err := dec.ctx.Err(); err != nil {
+				// The value will be scanned again from dec.scanp.
+				dec.scan.bytes -= int64(scanp - dec.scanp)
+				return 0, err
+			}
+		}
+
 		n := scanp - dec.scanp
 		err = dec.refill()
 		scanp = dec.scanp + n
@@ -196,6 +403,9 @@ type Encoder struct {
 	indentBuf    []byte
 	indentPrefix string
 	indentValue  string
+
+	omitPaths map[string]bool
+	nullPaths map[string]bool
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -216,6 +426,8 @@ func (enc *Encoder) Encode(v any) error {
 
 	e := newEncodeState()
 	defer encodeStatePool.Put(e)
+	e.omitPaths = enc.omitPaths
+	e.nullPaths = enc.nullPaths
 
 	err := e.marshal(v, encOpts{escapeHTML: enc.escapeHTML})
 	if err != nil {
@@ -495,9 +707,52 @@ func (dec *Decoder) More() bool {
 	return err == nil && c != ']' && c != '}'
 }
 
+// PeekKind reports the kind of the next JSON value in the input stream
+// without consuming it, so that a caller using the [Decoder.Token] API can
+// choose how to decode the value, with [Decoder.Decode] or token by token,
+// before doing so. It skips the comma or colon that precedes the value, if
+// any. At the end of the current array or object, it returns [KindInvalid]
+// and a nil error; at the end of the input, KindInvalid and [io.EOF].
+// The kind is that of the first byte of the value, which is not validated
+// further.
+func (dec *Decoder) PeekKind() (Kind, error) {
+	c, err := dec.peek()
+	if err != nil {
+		return KindInvalid, err
+	}
+	switch {
+	case c == ']' && (dec.tokenState == tokenArrayStart || dec.tokenState == tokenArrayComma),
+		c == '}' && (dec.tokenState == tokenObjectStart || dec.tokenState == tokenObjectComma):
+		return KindInvalid, nil
+	}
+	if dec.tokenState == tokenObjectComma && c == ',' {
+		// The next value is a key, which Decode does not expect.
+		dec.scanp++
+		dec.tokenState = tokenObjectKey
+	} else if err := dec.tokenPrepareForDecode(); err != nil {
+		return KindInvalid, err
+	}
+	if c, err = dec.peek(); err != nil {
+		return KindInvalid, err
+	}
+	switch c {
+	case 'n', 't', 'f', '"', '[', '{', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return kindOf([]byte{c}), nil
+	}
+	if dec.d.nonFinite(c) {
+		return KindNumber, nil
+	}
+	_, err = dec.tokenError(c)
+	return KindInvalid, err
+}
+
 func (dec *Decoder) peek() (byte, error) {
 	var err error
 	for {
+		if dec.skipBOM() && err == nil {
+			err = dec.refill()
+			continue
+		}
 		for i := dec.scanp; i < len(dec.buf); i++ {
 			c := dec.buf[i]
 			if isSpace(c) {
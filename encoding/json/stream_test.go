@@ -6,6 +6,8 @@ package json
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -18,6 +20,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TODO(https://go.dev/issue/52751): Replace with native testing support.
@@ -520,3 +523,168 @@ func TestHTTPDecoding(t *testing.T) {
 		t.Errorf("Decode error:\n\tgot:  %v\n\twant: io.EOF", err)
 	}
 }
+
+// cancelReader returns the data of r a byte at a time, and calls cancel
+// once n bytes have been read.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel func()
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		r.cancel()
+	}
+	r.n--
+	return r.r.Read(p[:1])
+}
+
+func TestDecodeContext(t *testing.T) {
+	const input = `{"a": [1, 2, 3], "b": "test"} {"c": 1}`
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dec := NewDecoder(&cancelReader{r: strings.NewReader(input), n: 10, cancel: cancel})
+
+	var v map[string]any
+	if err := dec.DecodeContext(ctx, &v); err != context.Canceled {
+		t.Fatalf("DecodeContext error:\n\tgot:  %v\n\twant: %v", err, context.Canceled)
+	}
+	if err := dec.DecodeContext(ctx, &v); err != context.Canceled {
+		t.Fatalf("DecodeContext error with done context:\n\tgot:  %v\n\twant: %v", err, context.Canceled)
+	}
+
+	// The canceled value can still be decoded.
+	if err := dec.DecodeContext(context.Background(), &v); err != nil {
+		t.Fatalf("DecodeContext error: %v", err)
+	}
+	want := map[string]any{"a": []any{1.0, 2.0, 3.0}, "b": "test"}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("DecodeContext:\n\tgot:  %v\n\twant: %v", v, want)
+	}
+	v = nil
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := map[string]any{"c": 1.0}; !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode:\n\tgot:  %v\n\twant: %v", v, want)
+	}
+	if got, want := dec.InputOffset(), int64(len(input)); got != want {
+		t.Fatalf("InputOffset = %d, want %d", got, want)
+	}
+}
+
+func TestDecodeTimeout(t *testing.T) {
+	input := `{"a": "` + strings.Repeat("x", 1000) + `"}`
+
+	// Cancel the first call halfway through the value, so that part of it is
+	// buffered when the timeouts below apply.
+	ctx, cancel := context.WithCancel(context.Background())
+	dec := NewDecoder(&cancelReader{r: strings.NewReader(input), n: 500, cancel: cancel})
+	var v map[string]string
+	if err := dec.DecodeContext(ctx, &v); err != context.Canceled {
+		t.Fatalf("DecodeContext error:\n\tgot:  %v\n\twant: %v", err, context.Canceled)
+	}
+
+	// A timeout that has already elapsed stops before reading.
+	offset := dec.InputOffset()
+	if err := dec.DecodeTimeout(0, &v); err != context.DeadlineExceeded {
+		t.Fatalf("DecodeTimeout error:\n\tgot:  %v\n\twant: %v", err, context.DeadlineExceeded)
+	}
+	if got := dec.InputOffset(); got != offset {
+		t.Fatalf("InputOffset after DecodeTimeout = %d, want %d", got, offset)
+	}
+
+	// With enough time, the rest of the value is read.
+	if err := dec.DecodeTimeout(time.Minute, &v); err != nil {
+		t.Fatalf("DecodeTimeout error: %v", err)
+	}
+	if len(v["a"]) != 1000 {
+		t.Fatalf("DecodeTimeout: got %d bytes, want 1000", len(v["a"]))
+	}
+}
+
+func TestDecodeContextSyntaxErrorOffset(t *testing.T) {
+	const input = `{"a": [1, 2, 3], "b": x}`
+
+	var want *SyntaxError
+	if err := NewDecoder(strings.NewReader(input)).Decode(new(any)); !errors.As(err, &want) {
+		t.Fatalf("Decode error:\n\tgot:  %v\n\twant: SyntaxError", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dec := NewDecoder(&cancelReader{r: strings.NewReader(input), n: 10, cancel: cancel})
+	if err := dec.DecodeContext(ctx, new(any)); err != context.Canceled {
+		t.Fatalf("DecodeContext error:\n\tgot:  %v\n\twant: %v", err, context.Canceled)
+	}
+	var got *SyntaxError
+	if err := dec.Decode(new(any)); !errors.As(err, &got) {
+		t.Fatalf("Decode error:\n\tgot:  %v\n\twant: SyntaxError", err)
+	}
+	if got.Offset != want.Offset {
+		t.Fatalf("SyntaxError.Offset after cancellation = %d, want %d", got.Offset, want.Offset)
+	}
+}
+
+func TestPeekKind(t *testing.T) {
+	const input = `{"a": [1, "s", true, false, null, {"x": 1}, [2], -3], "b": {"n": "x"}} 5`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch()
+	want := []struct {
+		kind  Kind
+		token Token // token read after peeking, if not decoded
+	}{
+		{KindObject, Delim('{')},
+		{KindString, "a"},
+		{KindArray, Delim('[')},
+		{KindNumber, 1.0},
+		{KindString, "s"},
+		{KindBool, true},
+		{KindBool, false},
+		{KindNull, nil},
+		{KindObject, Delim('{')},
+		{KindString, "x"},
+		{KindNumber, 1.0},
+		{KindInvalid, Delim('}')},
+		{KindArray, Delim('[')},
+		{KindNumber, 2.0},
+		{KindInvalid, Delim(']')},
+		{KindNumber, -3.0},
+		{KindInvalid, Delim(']')},
+		{KindString, "b"},
+		{KindObject, nil}, // decoded
+		{KindInvalid, Delim('}')},
+		{KindNumber, 5.0},
+	}
+	for i, w := range want {
+		kind, err := dec.PeekKind()
+		if err != nil || kind != w.kind {
+			t.Fatalf("%d: PeekKind = %v, %v, want %v, nil", i, kind, err, w.kind)
+		}
+		// Peeking twice does not consume anything.
+		if kind, err := dec.PeekKind(); err != nil || kind != w.kind {
+			t.Fatalf("%d: second PeekKind = %v, %v, want %v, nil", i, kind, err, w.kind)
+		}
+		if kind == KindObject && w.token == nil {
+			var v struct{ N int }
+			if err := dec.Decode(&v); err != nil || !dec.HadMismatch() {
+				t.Fatalf("%d: Decode = %v, mismatch %v, want a tolerated mismatch", i, err, dec.HadMismatch())
+			}
+			continue
+		}
+		tok, err := dec.Token()
+		if err != nil || tok != w.token {
+			t.Fatalf("%d: Token = %v, %v, want %v, nil", i, tok, err, w.token)
+		}
+	}
+	if kind, err := dec.PeekKind(); kind != KindInvalid || err != io.EOF {
+		t.Errorf("PeekKind at end of input = %v, %v, want %v, %v", kind, err, KindInvalid, io.EOF)
+	}
+
+	dec = NewDecoder(strings.NewReader(`[x]`))
+	dec.Token()
+	var serr *SyntaxError
+	if kind, err := dec.PeekKind(); kind != KindInvalid || !errors.As(err, &serr) {
+		t.Errorf("PeekKind before invalid value = %v, %v, want %v, SyntaxError", kind, err, KindInvalid)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Oscar Pernia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encoding_test checks that the json and xml packages agree on the
+// values they decode with type mismatches tolerated. It is kept apart so that
+// the tests of each package do not depend on the other.
+package encoding_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otaxhu/type-mismatch-encoding/encoding/json"
+	"github.com/otaxhu/type-mismatch-encoding/encoding/xml"
+)
+
+func TestMismatchedSlicesLikeXML(t *testing.T) {
+	type T struct {
+		Tags []string `json:"tags" xml:"tags>tag"`
+		Ints []int    `json:"ints" xml:"ints"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(`{"tags": "x", "ints": [1, "y", 3]}`))
+	dec.AllowTypeMismatch()
+	var fromJSON T
+	if err := dec.Decode(&fromJSON); err != nil {
+		t.Fatalf("json Decode error: %v", err)
+	}
+
+	xdec := xml.NewDecoder(strings.NewReader(`<t><tags>x</tags><ints>1</ints><ints>y</ints><ints>3</ints></t>`))
+	xdec.AllowTypeMismatch = true
+	var fromXML T
+	if err := xdec.Decode(&fromXML); err != nil {
+		t.Fatalf("xml Decode error: %v", err)
+	}
+
+	// Both leave the mismatched slice nil, and zero the mismatched element.
+	want := T{Ints: []int{1, 0, 3}}
+	for _, tc := range []struct {
+		name string
+		got  T
+	}{{"json", fromJSON}, {"xml", fromXML}} {
+		if !reflect.DeepEqual(tc.got, want) {
+			t.Errorf("%s Decode:\n\tgot:  %#v\n\twant: %#v", tc.name, tc.got, want)
+		}
+	}
+}
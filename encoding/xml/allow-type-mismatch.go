@@ -0,0 +1,246 @@
+// Copyright 2024 Oscar Pernia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"bytes"
+	"encoding"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A TypeMismatch describes the content of an XML element or attribute that
+// was not appropriate for a value of a specific Go type, and that a [Decoder]
+// tolerated because of [Decoder.AllowTypeMismatch] instead of returning an
+// error.
+type TypeMismatch struct {
+	Value  string       // content of the element or attribute
+	Type   reflect.Type // type of Go value it could not be assigned to
+	Path   string       // path from the root element, as in "a>b" or "a>b>@attr"
+	Offset int64        // mismatch was found after reading Offset bytes
+	Line   int          // line of the start tag of the element, starting at 1
+	Column int          // column of the '<' of the start tag, starting at 1
+
+	// ActualKind is the kind of content that was found, to tell apart an
+	// element with unexpected text from one with unexpected child elements.
+	ActualKind Kind
+
+	// Cause is the reason for the report, CauseTypeMismatch for all the
+	// entries but those tolerated because of [Decoder.AllowNameMismatch].
+	Cause Cause
+}
+
+// Error describes the mismatch, so that a single one can be used as an error,
+// as in "xml: type mismatch at a>b>@attr: expected int, got text".
+func (m TypeMismatch) Error() string {
+	msg := "xml: " + m.Cause.String()
+	if m.Path != "" {
+		msg += " at " + m.Path
+	}
+	typ := "<nil>"
+	if m.Type != nil {
+		typ = m.Type.String()
+	}
+	if m.Cause == CauseNameMismatch {
+		return msg + ": element " + m.Value + " decoded into " + typ
+	}
+	return msg + ": expected " + typ + ", got " + m.ActualKind.String()
+}
+
+// A Cause is the reason for a [TypeMismatch] report.
+type Cause int
+
+const (
+	// CauseTypeMismatch is content that was not appropriate for the Go
+	// value, and was tolerated.
+	CauseTypeMismatch Cause = iota
+
+	// CauseNameMismatch is an element whose name or name space is not the
+	// one of the XMLName field of the struct it was decoded into. Its Value
+	// is the name of the element, and its Type the type of the struct,
+	// which is decoded as usual.
+	CauseNameMismatch
+)
+
+func (c Cause) String() string {
+	switch c {
+	case CauseTypeMismatch:
+		return "type mismatch"
+	case CauseNameMismatch:
+		return "name mismatch"
+	}
+	return "Cause(" + strconv.Itoa(int(c)) + ")"
+}
+
+// A Kind classifies the content of a mismatched element or attribute.
+type Kind int
+
+const (
+	KindInvalid  Kind = iota // no content was classified
+	KindText                 // character data, as in <int>abc</int>
+	KindChildren             // child elements, as in <int><a/></int>
+	KindEmpty                // nothing, as in <int/>, <int></int> or attr=""
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindInvalid:
+		return "invalid"
+	case KindText:
+		return "text"
+	case KindChildren:
+		return "children"
+	case KindEmpty:
+		return "empty"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Mismatches returns the type mismatches tolerated by the most recent call to
+// [Decoder.Decode] or [Decoder.DecodeElement], in the order they were found.
+// It returns nil if the value was decoded without mismatches.
+func (d *Decoder) Mismatches() []TypeMismatch { return d.mismatches }
+
+// HadMismatch reports whether the most recent call to [Decoder.Decode] or
+// [Decoder.DecodeElement] tolerated any type mismatch. Name mismatches,
+// tolerated because of [Decoder.AllowNameMismatch], are not counted.
+func (d *Decoder) HadMismatch() bool {
+	for _, m := range d.mismatches {
+		if m.Cause == CauseTypeMismatch {
+			return true
+		}
+	}
+	return false
+}
+
+// WasMismatched reports whether the most recent call to [Decoder.Decode] or
+// [Decoder.DecodeElement] tolerated a type mismatch for the element or
+// attribute at path, as in [TypeMismatch.Path]. It tells apart a value that
+// is zero because its content was mismatched from one that is zero because
+// it was absent, which is never a mismatch. As with [Decoder.HadMismatch],
+// name mismatches are not counted.
+func (d *Decoder) WasMismatched(path string) bool {
+	for _, m := range d.mismatches {
+		if m.Cause == CauseTypeMismatch && m.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// saveMismatch records that src, the content of kind k of the element or
+// attribute name of the innermost open element, is not appropriate for dst,
+// and sets dst to its zero value.
+func (d *Decoder) saveMismatch(dst reflect.Value, src []byte, name string, k Kind) {
+	m := TypeMismatch{
+		Value:      string(src),
+		Type:       dst.Type(),
+		Path:       d.path(name),
+		Offset:     d.InputOffset(),
+		Line:       d.valueLine,
+		Column:     d.valueColumn,
+		ActualKind: k,
+	}
+	d.report(m)
+	dst.SetZero()
+}
+
+// unmarshalText calls u, the text unmarshaler of dst, with text, the content
+// of the element or attribute name. If it fails for a [time.Time], the text
+// is parsed with the layouts of [Decoder.TimeLayouts], and if none of them
+// matches, the failure is a type mismatch with [Decoder.AllowTypeMismatch].
+// Without layouts, it is returned as an error, as in encoding/json.
+func (d *Decoder) unmarshalText(dst reflect.Value, u encoding.TextUnmarshaler, text []byte, name string) error {
+	err := u.UnmarshalText(text)
+	if err == nil || dst.Type() != timeType || !dst.CanSet() {
+		return err
+	}
+	s := strings.TrimSpace(string(text))
+	for _, layout := range d.TimeLayouts {
+		if t, perr := time.Parse(layout, s); perr == nil {
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+	if d.AllowTypeMismatch && d.TimeLayouts != nil {
+		d.saveMismatch(dst, text, name, textKind(text))
+		return nil
+	}
+	return err
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// saveNameMismatch records that the innermost open element, named name, is
+// decoded into a value of type t despite the XMLName field of t.
+func (d *Decoder) saveNameMismatch(t reflect.Type, name Name) {
+	d.report(TypeMismatch{
+		Value:  name.Local,
+		Type:   t,
+		Path:   d.path(""),
+		Offset: d.InputOffset(),
+		Line:   d.valueLine,
+		Column: d.valueColumn,
+		Cause:  CauseNameMismatch,
+	})
+}
+
+// report passes m to the mismatch handler, and adds it to the mismatches.
+func (d *Decoder) report(m TypeMismatch) {
+	if d.MismatchHandler != nil {
+		d.MismatchHandler(m)
+	}
+	d.mismatches = append(d.mismatches, m)
+}
+
+// textKind returns the kind of src, the character data of an element without
+// children or an attribute value.
+func textKind(src []byte) Kind {
+	if len(src) == 0 {
+		return KindEmpty
+	}
+	return KindText
+}
+
+// path returns the names of the open elements followed by name, separated
+// by '>', or only the names of the open elements if name is empty.
+func (d *Decoder) path(name string) string {
+	var names []string
+	for s := d.stk; s != nil; s = s.next {
+		if s.kind == stkStart {
+			names = append(names, s.name.Local)
+		}
+	}
+	slices.Reverse(names)
+	if name != "" {
+		names = append(names, name)
+	}
+	return strings.Join(names, ">")
+}
+
+// UnmarshalTyped parses the XML-encoded data into a new value of type T, as
+// [Unmarshal] does, but tolerating type mismatches as a [Decoder] does with
+// [Decoder.AllowTypeMismatch] set. It returns the decoded value together with
+// the mismatches that were tolerated, which is nil if there were none.
+func UnmarshalTyped[T any](data []byte) (T, []TypeMismatch, error) {
+	var v T
+	d := NewDecoder(bytes.NewReader(data))
+	d.AllowTypeMismatch = true
+	err := d.Decode(&v)
+	return v, d.Mismatches(), err
+}
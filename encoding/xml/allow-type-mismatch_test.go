@@ -15,9 +15,14 @@
 package xml
 
 import (
+	"encoding"
+	"errors"
+	"io"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAllowTypeMismatchDecode(t *testing.T) {
@@ -40,7 +45,11 @@ func TestAllowTypeMismatchDecode(t *testing.T) {
 			a.Float64 == b.Float64 &&
 			slices.Equal(a.SliceString, b.SliceString) &&
 			slices.Equal(a.SliceInt, b.SliceInt) &&
-			slices.Equal(a.SliceFloat64, b.SliceFloat64)
+			slices.Equal(a.SliceFloat64, b.SliceFloat64) &&
+			// A slice without elements must be left nil, as in encoding/json.
+			(a.SliceString == nil) == (b.SliceString == nil) &&
+			(a.SliceInt == nil) == (b.SliceInt == nil) &&
+			(a.SliceFloat64 == nil) == (b.SliceFloat64 == nil)
 	}
 	baseT := T{
 		String:  "test",
@@ -212,7 +221,7 @@ func TestAllowTypeMismatchDecode(t *testing.T) {
 			`,
 			expectedT: func() T {
 				ret := baseT
-				ret.SliceString = []string{}
+				ret.SliceString = nil
 				return ret
 			},
 		},
@@ -414,3 +423,1047 @@ func TestAllowTypeMismatchDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestAllowTypeMismatchSliceOfStructs(t *testing.T) {
+	type Item struct {
+		Name string `xml:"name"`
+		Age  int    `xml:"age"`
+	}
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Items   []Item   `xml:"item"`
+		After   int      `xml:"after"`
+	}
+
+	input := Header + `
+		<t>
+			<item><name>a</name><age>1</age></item>
+			<item><name>b</name><age>MISMATCHED_TYPE</age></item>
+			<item><name>c</name><age>3.5</age></item>
+			<item><name>d</name><age>4</age></item>
+			<after>5</after>
+		</t>
+	`
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch = true
+	// Reuse a backing array holding stale elements, which must not leak
+	// into the decoded elements whose age mismatches.
+	stale := []Item{{"x", 9}, {"x", 9}, {"x", 9}, {"x", 9}}
+	got := T{Items: stale[:0]}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Item{{"a", 1}, {"b", 0}, {"c", 0}, {"d", 4}}
+	if !slices.Equal(got.Items, want) || got.After != 5 {
+		t.Fatalf("expected:\n\t%v\ngot:\n\t%v", T{Items: want, After: 5}, got)
+	}
+}
+
+func TestAllowTypeMismatchCharData(t *testing.T) {
+	type Value struct {
+		Unit string `xml:"unit,attr"`
+		Val  int    `xml:",chardata"`
+	}
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Values  []Value  `xml:"value"`
+		Float   struct {
+			Val float64 `xml:",chardata"`
+		} `xml:"float"`
+	}
+
+	input := Header + `
+		<t>
+			<value unit="kg">1</value>
+			<value unit="kg">MISMATCHED_TYPE</value>
+			<value unit="g"> 3 </value>
+			<float>1.5.5</float>
+		</t>
+	`
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch = true
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Value{{"kg", 1}, {"kg", 0}, {"g", 3}}
+	if !slices.Equal(got.Values, want) || got.Float.Val != 0 {
+		t.Fatalf("expected:\n\t%v\ngot:\n\t%v", want, got)
+	}
+
+	dec = NewDecoder(strings.NewReader(input))
+	if err := dec.Decode(&got); err == nil {
+		t.Fatal("expected an error when AllowTypeMismatch is false")
+	}
+}
+
+func TestReplaceInvalidUTF8(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Attr    string   `xml:"attr,attr"`
+		String  string   `xml:"string"`
+		Int     int      `xml:"int"`
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		charsetReader bool
+	}{
+		{
+			name:  "UTF8",
+			input: Header + "<t attr=\"a\xffb\"><string>a\xfe\xffb</string><int>1\xff</int></t>",
+		},
+		{
+			name:          "CharsetReader",
+			input:         `<?xml version="1.0" encoding="x-identity"?>` + "<t attr=\"a\xffb\"><string>a\xfe\xffb</string><int>1\xff</int></t>",
+			charsetReader: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			newDecoder := func() *Decoder {
+				dec := NewDecoder(strings.NewReader(tc.input))
+				if tc.charsetReader {
+					dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+						return input, nil
+					}
+				}
+				return dec
+			}
+
+			var got T
+			if err := newDecoder().Decode(&got); err == nil {
+				t.Fatal("expected an error when ReplaceInvalidUTF8 is false")
+			}
+
+			dec := newDecoder()
+			dec.ReplaceInvalidUTF8 = true
+			dec.AllowTypeMismatch = true
+			got = T{}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Attr != "a�b" || got.String != "a�b" || got.Int != 0 {
+				t.Fatalf("expected invalid UTF-8 to be replaced, got:\n\t%+q", got)
+			}
+		})
+	}
+}
+
+func TestAllowTypeMismatchSurroundingSpace(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Attr    int      `xml:"attr,attr"`
+		Int     int      `xml:"int"`
+		Uint    uint     `xml:"uint"`
+		Float   float64  `xml:"float"`
+		Bool    bool     `xml:"bool"`
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		want  T
+	}{
+		{
+			name:  "Spaces",
+			input: `<t attr=" 7 "><int> 123 </int><uint> 4 </uint><float> 12.5 </float><bool> true </bool></t>`,
+			want:  T{Attr: 7, Int: 123, Uint: 4, Float: 12.5, Bool: true},
+		},
+		{
+			name:  "TabsAndNewlines",
+			input: "<t attr=\"\t7\n\"><int>\n\t123\n</int><uint>\t4\t</uint><float>\r\n12.5\r\n</float><bool>\n1\n</bool></t>",
+			want:  T{Attr: 7, Int: 123, Uint: 4, Float: 12.5, Bool: true},
+		},
+		{
+			name:  "InnerSpace",
+			input: `<t attr="7 7"><int> 1 23 </int><uint> 4 </uint><float> 12 .5 </float><bool> tr ue </bool></t>`,
+			want:  T{Uint: 4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch = true
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAllowTypeMismatchChildElements(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Int     int      `xml:"int"`
+		Uint    uint     `xml:"uint"`
+		Float   float64  `xml:"float"`
+		Bool    bool     `xml:"bool"`
+		String  string   `xml:"string"`
+		After   int      `xml:"after"`
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		want  T
+	}{
+		{
+			name:  "Child",
+			input: `<t><int><unexpected>1</unexpected></int><after>1</after></t>`,
+			want:  T{Float: -1, Uint: 1, Bool: true, After: 1},
+		},
+		{
+			name:  "CharDataAroundChild",
+			input: `<t><int>1<unexpected/>2</int><uint>3<x>4</x></uint><after>1</after></t>`,
+			want:  T{Float: -1, Bool: true, After: 1},
+		},
+		{
+			name: "NestedChildren",
+			input: `<t><float><a><b><c>1.5</c></b><int>2</int></a></float>` +
+				`<bool><after>3</after>true</bool><after>1</after></t>`,
+			want: T{Int: -1, Uint: 1, After: 1},
+		},
+		{
+			name:  "String",
+			input: `<t><string>a<b>c</b>d</string><int>2</int><after>1</after></t>`,
+			want:  T{String: "ad", Int: 2, Uint: 1, Float: -1, Bool: true, After: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch = true
+			got := T{Int: -1, Uint: 1, Float: -1, Bool: true}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", tc.want, got)
+			}
+			if _, err := dec.Token(); err != io.EOF {
+				t.Fatalf("expected io.EOF after the document, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEmptyElementIsMismatch(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Attr    int      `xml:"attr,attr"`
+		Int     int      `xml:"int"`
+		Uint    uint     `xml:"uint"`
+		Float   float64  `xml:"float"`
+		Bool    bool     `xml:"bool"`
+		String  string   `xml:"string"`
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "Empty",
+			input: `<t attr=""><int></int><uint></uint><float></float><bool></bool><string></string></t>`,
+		},
+		{
+			name:  "SelfClosing",
+			input: `<t attr=""><int/><uint/><float/><bool/><string/></t>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seed := T{Attr: 1, Int: 1, Uint: 1, Float: 1, Bool: true, String: "a"}
+
+			// By default, empty values are zero values without a mismatch.
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch = true
+			got := seed
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != (T{}) {
+				t.Fatalf("expected the zero value, got:\n\t%+v", got)
+			}
+			if m := dec.Mismatches(); m != nil {
+				t.Fatalf("expected no mismatches, got:\n\t%+v", m)
+			}
+
+			dec = NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch = true
+			dec.EmptyElementIsMismatch = true
+			got = seed
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != (T{}) {
+				t.Fatalf("expected the zero value, got:\n\t%+v", got)
+			}
+			var paths []string
+			for _, m := range dec.Mismatches() {
+				paths = append(paths, m.Path)
+			}
+			want := []string{"t>@attr", "t>int", "t>uint", "t>float", "t>bool"}
+			if !slices.Equal(paths, want) {
+				t.Fatalf("expected mismatches at:\n\t%q\ngot:\n\t%q", want, paths)
+			}
+
+			// Without AllowTypeMismatch, the mismatches are errors.
+			dec = NewDecoder(strings.NewReader(tc.input))
+			dec.EmptyElementIsMismatch = true
+			if err := dec.Decode(&got); err == nil {
+				t.Fatal("expected an error when AllowTypeMismatch is false")
+			}
+		})
+	}
+}
+
+func TestMismatches(t *testing.T) {
+	type Inner struct {
+		Val int `xml:",chardata"`
+	}
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Attr    bool     `xml:"attr,attr"`
+		Ints    []int    `xml:"ints>int"`
+		Inner   Inner    `xml:"inner"`
+		Float   float32  `xml:"float"`
+	}
+
+	input := `<t attr="yes"><ints><int>1</int><int>x</int></ints><inner>y</inner><float>1<z/></float></t>`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch = true
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []TypeMismatch{
+		{Value: "yes", Type: reflect.TypeFor[bool](), Path: "t>@attr", Offset: 14, Line: 1, Column: 1, ActualKind: KindText},
+		{Value: "x", Type: reflect.TypeFor[int](), Path: "t>ints>int", Offset: 44, Line: 1, Column: 33, ActualKind: KindText},
+		{Value: "y", Type: reflect.TypeFor[int](), Path: "t>inner", Offset: 67, Line: 1, Column: 52, ActualKind: KindText},
+		{Value: "1", Type: reflect.TypeFor[float32](), Path: "t>float", Offset: 87, Line: 1, Column: 68, ActualKind: KindChildren},
+	}
+	if m := dec.Mismatches(); !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, m)
+	}
+
+	// The mismatches are reset by every call to Decode.
+	dec = NewDecoder(strings.NewReader(input + `<t attr="true"/>`))
+	dec.AllowTypeMismatch = true
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if m := dec.Mismatches(); m != nil {
+		t.Fatalf("expected no mismatches for the second document, got:\n\t%+v", m)
+	}
+}
+
+func TestUnmarshalTyped(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		String  string   `xml:"string"`
+		Int     int      `xml:"int"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		got, mismatches, err := UnmarshalTyped[T]([]byte(`<t><string>test</string><int>123</int></t>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (T{String: "test", Int: 123}); got != want {
+			t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, got)
+		}
+		if mismatches != nil {
+			t.Fatalf("expected no mismatches, got:\n\t%+v", mismatches)
+		}
+	})
+
+	t.Run("Mismatched", func(t *testing.T) {
+		got, mismatches, err := UnmarshalTyped[*T]([]byte(`<t><string>test</string><int>MISMATCHED_TYPE</int></t>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (T{String: "test"}); got == nil || *got != want {
+			t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, got)
+		}
+		want := []TypeMismatch{{Value: "MISMATCHED_TYPE", Type: reflect.TypeFor[int](), Path: "t>int", Offset: 50, Line: 1, Column: 25, ActualKind: KindText}}
+		if !reflect.DeepEqual(mismatches, want) {
+			t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, mismatches)
+		}
+	})
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		_, _, err := UnmarshalTyped[T]([]byte(`<t><string>test</string>`))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("expected a *SyntaxError, got %v", err)
+		}
+	})
+}
+
+func TestHadMismatch(t *testing.T) {
+	type T struct {
+		Int int `xml:"int"`
+	}
+
+	dec := NewDecoder(strings.NewReader(`<t><int>1</int></t><t><int>x</int></t><t><int>3</int></t><t><int>4<a/></int></t>`))
+	dec.AllowTypeMismatch = true
+	for i, want := range []bool{false, true, false, true} {
+		var got T
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if dec.HadMismatch() != want {
+			t.Fatalf("document %d: expected HadMismatch to be %v", i, want)
+		}
+	}
+}
+
+func TestWasMismatched(t *testing.T) {
+	type T struct {
+		XMLName struct{} `xml:"t"`
+		Attr    int      `xml:"attr,attr"`
+		Int     int      `xml:"int"`
+		Uint    uint     `xml:"uint"`
+		Zip     int      `xml:"addr>zip"`
+	}
+
+	dec := NewDecoder(strings.NewReader(`<t attr="a"><int>1</int><addr><zip>x</zip></addr></t>`))
+	dec.AllowTypeMismatch = true
+	var got T
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"t>@attr":    true,
+		"t>int":      false,
+		"t>uint":     false, // absent
+		"t>addr>zip": true,
+		"t>addr":     false,
+	} {
+		if dec.WasMismatched(path) != want {
+			t.Errorf("expected WasMismatched(%q) to be %v", path, want)
+		}
+	}
+}
+
+func TestAllowTypeMismatchMixedContent(t *testing.T) {
+	type Para struct {
+		XMLName struct{} `xml:"p"`
+		Text    string   `xml:",chardata"`
+		Count   int      `xml:"count"`
+		Ratio   float64  `xml:"ratio"`
+		Bold    []string `xml:"b"`
+	}
+
+	input := `<p>Found <count>many</count> items, <b>bold</b> at a ratio of <ratio>0.5</ratio>.</p>`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch = true
+	got := Para{Count: -1}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Para{Text: "Found  items,  at a ratio of .", Ratio: 0.5, Bold: []string{"bold"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, got)
+	}
+	if paths := dec.Mismatches(); len(paths) != 1 || paths[0].Path != "p>count" {
+		t.Fatalf("expected a single mismatch at p>count, got:\n\t%+v", paths)
+	}
+
+	// Numeric character data is not a mismatch because of the child elements
+	// of its struct.
+	type Measure struct {
+		XMLName   struct{} `xml:"m"`
+		Val       int      `xml:",chardata"`
+		Unit      string   `xml:"unit"`
+		Precision int      `xml:"precision"`
+	}
+	dec = NewDecoder(strings.NewReader(`<m>12<unit>kg</unit><precision>high</precision></m>`))
+	dec.AllowTypeMismatch = true
+	var m Measure
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Measure{Val: 12, Unit: "kg"}); m != want {
+		t.Fatalf("expected:\n\t%+v\ngot:\n\t%+v", want, m)
+	}
+	if !dec.WasMismatched("m>precision") || len(dec.Mismatches()) != 1 {
+		t.Fatalf("expected a single mismatch at m>precision, got:\n\t%+v", dec.Mismatches())
+	}
+}
+
+func TestMismatchPosition(t *testing.T) {
+	type Item struct {
+		ID    int     `xml:"id,attr"`
+		Price float64 `xml:"price"`
+	}
+	type T struct {
+		Items []Item `xml:"item"`
+		Count int    `xml:"count"`
+	}
+
+	input := `<t>
+	<item id="1">
+		<price>9.5</price>
+	</item>
+	<item id="two">
+		<price>
+			free
+		</price>
+	</item>
+	<count><n/></count>
+</t>`
+	d := NewDecoder(strings.NewReader(input))
+	d.AllowTypeMismatch = true
+	var v T
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	type pos struct {
+		Path         string
+		Line, Column int
+	}
+	want := []pos{
+		{"t>item>@id", 5, 2},
+		{"t>item>price", 6, 3},
+		{"t>count", 10, 2},
+	}
+	var got []pos
+	for _, m := range d.Mismatches() {
+		got = append(got, pos{m.Path, m.Line, m.Column})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected:\n\t%+v\ngot:\n\t%+v", want, got)
+	}
+}
+
+func TestMismatchHandlerDecodeElement(t *testing.T) {
+	type Item struct {
+		ID    int     `xml:"id,attr"`
+		Price float64 `xml:"price"`
+	}
+
+	input := `<feed>
+	<title>ignored</title>
+	<item id="1"><price>9.5</price></item>
+	<item id="x"><price>free</price></item>
+	<note>skipped</note>
+	<item id="3"><price>2,5</price></item>
+</feed>`
+	d := NewDecoder(strings.NewReader(input))
+	d.AllowTypeMismatch = true
+	var handled []string
+	d.MismatchHandler = func(m TypeMismatch) {
+		handled = append(handled, m.Path+"="+m.Value)
+	}
+	var items []Item
+	var mismatches []string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Token error: %v", err)
+		}
+		start, ok := tok.(StartElement)
+		if !ok || start.Name.Local != "item" {
+			continue
+		}
+		var item Item
+		if err := d.DecodeElement(&item, &start); err != nil {
+			t.Fatalf("DecodeElement error: %v", err)
+		}
+		items = append(items, item)
+		for _, m := range d.Mismatches() {
+			mismatches = append(mismatches, m.Path)
+		}
+	}
+
+	wantItems := []Item{{ID: 1, Price: 9.5}, {Price: 0}, {ID: 3}}
+	if !slices.Equal(items, wantItems) {
+		t.Errorf("items:\n\texpected: %+v\n\tgot:      %+v", wantItems, items)
+	}
+	wantHandled := []string{"feed>item>@id=x", "feed>item>price=free", "feed>item>price=2,5"}
+	if !slices.Equal(handled, wantHandled) {
+		t.Errorf("handled:\n\texpected: %q\n\tgot:      %q", wantHandled, handled)
+	}
+	wantMismatches := []string{"feed>item>@id", "feed>item>price", "feed>item>price"}
+	if !slices.Equal(mismatches, wantMismatches) {
+		t.Errorf("mismatches:\n\texpected: %q\n\tgot:      %q", wantMismatches, mismatches)
+	}
+}
+
+func TestMismatchActualKind(t *testing.T) {
+	type T struct {
+		Attr  int     `xml:"attr,attr"`
+		Int   int     `xml:"int"`
+		Float float64 `xml:"float"`
+		Bool  bool    `xml:"bool"`
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		path  string
+		want  Kind
+	}{
+		{name: "Text", input: `<t><int>abc</int></t>`, path: "t>int", want: KindText},
+		{name: "Spaces", input: `<t><float>  </float></t>`, path: "t>float", want: KindText},
+		{name: "AttrText", input: `<t attr="x"/>`, path: "t>@attr", want: KindText},
+		{name: "Children", input: `<t><int><n>1</n></int></t>`, path: "t>int", want: KindChildren},
+		{name: "TextAndChildren", input: `<t><bool>true<b/></bool></t>`, path: "t>bool", want: KindChildren},
+		{name: "Empty", input: `<t><int/></t>`, path: "t>int", want: KindEmpty},
+		{name: "EmptyTags", input: `<t><bool></bool></t>`, path: "t>bool", want: KindEmpty},
+		{name: "AttrEmpty", input: `<t attr=""/>`, path: "t>@attr", want: KindEmpty},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowTypeMismatch = true
+			dec.EmptyElementIsMismatch = true
+			var got T
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			m := dec.Mismatches()
+			if len(m) != 1 || m[0].Path != tc.path {
+				t.Fatalf("expected one mismatch for %s, got:\n\t%+v", tc.path, m)
+			}
+			if m[0].ActualKind != tc.want {
+				t.Fatalf("expected kind %v, got %v", tc.want, m[0].ActualKind)
+			}
+		})
+	}
+
+	for k, want := range map[Kind]string{
+		KindInvalid:  "invalid",
+		KindText:     "text",
+		KindChildren: "children",
+		KindEmpty:    "empty",
+		Kind(9):      "Kind(9)",
+	} {
+		if got := k.String(); got != want {
+			t.Errorf("expected Kind(%d).String() to be %q, got %q", int(k), want, got)
+		}
+	}
+}
+
+func TestAllowTypeMismatchNonScalarAttr(t *testing.T) {
+	type S struct{ X int }
+	type T struct {
+		Struct    S                        `xml:"struct,attr"`
+		Ptr       *S                       `xml:"ptr,attr"`
+		Slice     []S                      `xml:"slice,attr"`
+		Map       map[string]int           `xml:"map,attr"`
+		Iface     encoding.TextUnmarshaler `xml:"iface,attr"`
+		PtrToPtr  **int                    `xml:"ptrToPtr,attr"`
+		Strings   []string                 `xml:"strings,attr"`
+		Following int                      `xml:"following"`
+	}
+	const input = `<t struct="1" ptr="2" slice="3" map="4" iface="5" ptrToPtr="6" strings="7"><following>8</following></t>`
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.AllowTypeMismatch = true
+	got := T{Struct: S{1}, Map: map[string]int{"a": 1}}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Struct != (S{}) || got.Ptr != nil || got.Slice != nil || got.Map != nil || got.Iface != nil {
+		t.Errorf("expected the non-scalar attribute fields to be zero, got %+v", got)
+	}
+	if got.PtrToPtr == nil || **got.PtrToPtr != 6 || !slices.Equal(got.Strings, []string{"7"}) || got.Following != 8 {
+		t.Errorf("expected the other fields to be decoded, got %+v", got)
+	}
+	var paths []string
+	for _, m := range dec.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	wantPaths := []string{"t>@struct", "t>@ptr", "t>@slice", "t>@map", "t>@iface"}
+	if !slices.Equal(paths, wantPaths) {
+		t.Errorf("mismatches:\n\texpected: %q\n\tgot:      %q", wantPaths, paths)
+	}
+
+	// Without tolerance, the error is the one of strict decoding, and a nil
+	// interface does not panic.
+	for _, tc := range []struct{ input, want string }{
+		{`<t struct="1"/>`, "cannot unmarshal into xml.S"},
+		{`<t map="1"/>`, "cannot unmarshal into map[string]int"},
+		{`<t iface="1"/>`, "cannot unmarshal into encoding.TextUnmarshaler"},
+	} {
+		err := Unmarshal([]byte(tc.input), new(T))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("Unmarshal(%s): expected error %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
+
+func TestAllowNameMismatch(t *testing.T) {
+	type Entry struct {
+		XMLName Name   `xml:"entry"`
+		Title   string `xml:"title"`
+	}
+	type Feed struct {
+		XMLName Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      int     `xml:"id"`
+		Entries []Entry `xml:"item"`
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		paths []string
+		want  Name
+	}{
+		{
+			name:  "Matching",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom"><id>1</id></feed>`,
+			want:  Name{Space: "http://www.w3.org/2005/Atom", Local: "feed"},
+		},
+		{
+			name:  "RootName",
+			input: `<rss xmlns="http://www.w3.org/2005/Atom"><id>1</id></rss>`,
+			paths: []string{"rss"},
+			want:  Name{Space: "http://www.w3.org/2005/Atom", Local: "rss"},
+		},
+		{
+			name:  "RootNameSpace",
+			input: `<feed><id>1</id></feed>`,
+			paths: []string{"feed"},
+			want:  Name{Local: "feed"},
+		},
+		{
+			name:  "Nested",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom"><id>1</id><item><title>a</title></item></feed>`,
+			paths: []string{"feed>item"},
+			want:  Name{Space: "http://www.w3.org/2005/Atom", Local: "feed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.input))
+			dec.AllowNameMismatch = true
+			var handled []TypeMismatch
+			dec.MismatchHandler = func(m TypeMismatch) { handled = append(handled, m) }
+			var got Feed
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.XMLName != tc.want || got.ID != 1 {
+				t.Errorf("expected %v with ID 1, got %+v", tc.want, got)
+			}
+			var paths []string
+			for _, m := range dec.Mismatches() {
+				if m.Cause != CauseNameMismatch || m.Line != 1 || m.ActualKind != KindInvalid {
+					t.Errorf("unexpected mismatch %+v", m)
+				}
+				paths = append(paths, m.Path)
+			}
+			if !slices.Equal(paths, tc.paths) {
+				t.Errorf("mismatches:\n\texpected: %q\n\tgot:      %q", tc.paths, paths)
+			}
+			if !reflect.DeepEqual(handled, dec.Mismatches()) {
+				t.Errorf("expected the handler to be called with the mismatches, got %+v", handled)
+			}
+			// Name mismatches are not type mismatches.
+			if dec.HadMismatch() {
+				t.Error("expected HadMismatch to be false")
+			}
+			for _, path := range tc.paths {
+				if dec.WasMismatched(path) {
+					t.Errorf("expected WasMismatched(%q) to be false", path)
+				}
+			}
+		})
+	}
+
+	// Without AllowNameMismatch, and even with AllowTypeMismatch, the name is
+	// validated.
+	dec := NewDecoder(strings.NewReader(`<rss xmlns="http://www.w3.org/2005/Atom"/>`))
+	dec.AllowTypeMismatch = true
+	var uerr UnmarshalError
+	if err := dec.Decode(new(Feed)); !errors.As(err, &uerr) {
+		t.Errorf("expected an UnmarshalError, got %v", err)
+	}
+
+	for c, want := range map[Cause]string{
+		CauseTypeMismatch: "type mismatch",
+		CauseNameMismatch: "name mismatch",
+		Cause(5):          "Cause(5)",
+	} {
+		if got := c.String(); got != want {
+			t.Errorf("expected Cause(%d).String() to be %q, got %q", int(c), want, got)
+		}
+	}
+}
+
+func TestTimeLayouts(t *testing.T) {
+	type Item struct {
+		Updated time.Time  `xml:"updated,attr"`
+		Date    time.Time  `xml:"date"`
+		Expires *time.Time `xml:"expires"`
+	}
+	type Feed struct {
+		Items []Item `xml:"item"`
+	}
+	input := `<feed>` +
+		`<item updated="2024-03-04T05:06:07Z"><date>2024-03-04T05:06:07Z</date></item>` +
+		`<item updated="04/03/2024 05:06"><date> Mon, 04 Mar 2024 05:06:07 +0000 </date><expires>04/03/2024 05:06</expires></item>` +
+		`<item updated="yesterday"><date>2024-13-01</date><expires/></item>` +
+		`</feed>`
+	layouts := []string{"02/01/2006 15:04", time.RFC1123Z}
+	rfc := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	short := time.Date(2024, 3, 4, 5, 6, 0, 0, time.UTC)
+
+	d := NewDecoder(strings.NewReader(input))
+	d.AllowTypeMismatch = true
+	d.TimeLayouts = layouts
+	var got Feed
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := Feed{Items: []Item{
+		{Updated: rfc, Date: rfc},
+		{Updated: short, Date: rfc.In(got.Items[1].Date.Location()), Expires: &short},
+		{Expires: &time.Time{}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range d.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"feed>item>@updated", "feed>item>date", "feed>item>expires"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+
+	// Without AllowTypeMismatch, the layouts are still tried, but content
+	// that none matches is an error.
+	d = NewDecoder(strings.NewReader(`<item updated="04/03/2024 05:06"><date>04/03/2024 05:06</date></item>`))
+	d.TimeLayouts = layouts
+	var item Item
+	if err := d.Decode(&item); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if !item.Updated.Equal(short) || !item.Date.Equal(short) {
+		t.Errorf("Decode: got %+v, want times %v", item, short)
+	}
+	d = NewDecoder(strings.NewReader(`<item><date>yesterday</date></item>`))
+	d.TimeLayouts = layouts
+	if err := d.Decode(&item); err == nil {
+		t.Errorf("Decode: no error for a date that no layout matches")
+	}
+
+	// Without layouts, invalid content is an error even with
+	// AllowTypeMismatch.
+	d = NewDecoder(strings.NewReader(`<item><date>yesterday</date></item>`))
+	d.AllowTypeMismatch = true
+	if err := d.Decode(&item); err == nil || d.HadMismatch() {
+		t.Errorf("Decode without layouts: error %v, mismatch %v, want an error", err, d.HadMismatch())
+	}
+}
+
+// Box is a generic type, whose instances are decoded with their type
+// arguments.
+type Box[T any] struct {
+	Val []T `xml:"val"`
+	ID  T   `xml:"id,attr"`
+}
+
+func TestAllowTypeMismatchGeneric(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`<box id="x"><val>1</val><val>two</val><val><a/></val></box>`))
+	d.AllowTypeMismatch = true
+	var got Box[int]
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if want := (Box[int]{Val: []int{1, 0, 0}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range d.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"box>@id", "box>val", "box>val"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+}
+
+func TestAllowTypeMismatchRepeatedScalar(t *testing.T) {
+	type T struct {
+		Int  int      `xml:"int"`
+		Ptr  *int     `xml:"ptr"`
+		Bool bool     `xml:"bool"`
+		Strs []string `xml:"str"`
+	}
+	testCases := []struct {
+		name    string
+		input   string
+		want    T
+		offsets []int64
+	}{
+		{
+			name:  "LastValid",
+			input: `<t><int>x</int><int>2</int><int>3</int></t>`,
+			want:  T{Int: 3},
+			// The first one is reported even though it was replaced.
+			offsets: []int64{15},
+		},
+		{
+			name:    "LastInvalid",
+			input:   `<t><int>1</int><int>2</int><int>x</int></t>`,
+			want:    T{},
+			offsets: []int64{39},
+		},
+		{
+			name:    "LastChildren",
+			input:   `<t><bool>true</bool><bool><a/></bool></t>`,
+			want:    T{},
+			offsets: []int64{37},
+		},
+		{
+			name:    "Pointer",
+			input:   `<t><ptr>1</ptr><ptr>x</ptr></t>`,
+			want:    T{Ptr: new(int)},
+			offsets: []int64{27},
+		},
+		{
+			name:    "Slice",
+			input:   `<t><int>1</int><str>a</str><int>y</int><str>b</str></t>`,
+			want:    T{Strs: []string{"a", "b"}},
+			offsets: []int64{39},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tc.input))
+			d.AllowTypeMismatch = true
+			var got T
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, tc.want)
+			}
+			var offsets []int64
+			for _, m := range d.Mismatches() {
+				offsets = append(offsets, m.Offset)
+			}
+			if !slices.Equal(offsets, tc.offsets) {
+				t.Errorf("mismatch offsets = %v, want %v", offsets, tc.offsets)
+			}
+		})
+	}
+}
+
+func TestTypeMismatchError(t *testing.T) {
+	type Item struct {
+		XMLName Name `xml:"item"`
+		Price   int  `xml:"price"`
+		ID      int  `xml:"id,attr"`
+	}
+	type T struct {
+		Items []Item `xml:"item"`
+	}
+	d := NewDecoder(strings.NewReader(`<t><item id="x"><price><a/></price></item><item><price/></item></t>`))
+	d.AllowTypeMismatch = true
+	d.EmptyElementIsMismatch = true
+	if err := d.Decode(new(T)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	var got []string
+	for _, m := range d.Mismatches() {
+		got = append(got, m.Error())
+	}
+	want := []string{
+		"xml: type mismatch at t>item>@id: expected int, got text",
+		"xml: type mismatch at t>item>price: expected int, got children",
+		"xml: type mismatch at t>item>price: expected int, got empty",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Error:\n\tgot:  %q\n\twant: %q", got, want)
+	}
+
+	d = NewDecoder(strings.NewReader(`<entry><price>1</price></entry>`))
+	d.AllowNameMismatch = true
+	if err := d.Decode(new(Item)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if len(d.Mismatches()) != 1 {
+		t.Fatalf("Decode: %d mismatches, want 1", len(d.Mismatches()))
+	}
+	var err error = d.Mismatches()[0]
+	if got, want := err.Error(), "xml: name mismatch at entry: element entry decoded into xml.Item"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+}
+
+func TestAllowTypeMismatchAny(t *testing.T) {
+	type Caught struct {
+		XMLName Name
+		ID      int     `xml:"id,attr"`
+		N       int     `xml:"n"`
+		Price   float64 `xml:"price"`
+	}
+	type T struct {
+		Known int      `xml:"known"`
+		Other []Caught `xml:",any"`
+	}
+	input := `<t>` +
+		`<known>1</known>` +
+		`<foo id="7"><n>1</n><price>2.5</price></foo>` +
+		`<bar id="x"><n>y</n><price>3</price></bar>` +
+		`<baz><n><q/></n><price>1.5</price></baz>` +
+		`</t>`
+
+	d := NewDecoder(strings.NewReader(input))
+	d.AllowTypeMismatch = true
+	var got T
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := T{
+		Known: 1,
+		Other: []Caught{
+			{XMLName: Name{Local: "foo"}, ID: 7, N: 1, Price: 2.5},
+			{XMLName: Name{Local: "bar"}, Price: 3},
+			{XMLName: Name{Local: "baz"}, Price: 1.5},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode:\n\tgot:  %+v\n\twant: %+v", got, want)
+	}
+	var paths []string
+	for _, m := range d.Mismatches() {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"t>bar>@id", "t>bar>n", "t>baz>n"}; !slices.Equal(paths, want) {
+		t.Errorf("mismatched paths = %q, want %q", paths, want)
+	}
+
+	// Without AllowTypeMismatch, the first mismatch of a caught element is
+	// an error.
+	d = NewDecoder(strings.NewReader(`<t><bar><n>y</n></bar></t>`))
+	if err := d.Decode(new(T)); err == nil {
+		t.Errorf("Decode: no error for a mismatched caught element")
+	}
+}
@@ -6,6 +6,7 @@ package xml
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // BUG(rsc): Mapping between XML elements and data structures is inherently flawed:
@@ -140,6 +142,30 @@ func (d *Decoder) Decode(v any) error {
 	return d.DecodeElement(v, nil)
 }
 
+// DecodeContext works like [Decoder.Decode], but it stops decoding and returns
+// ctx.Err() once ctx is done. The context is checked before reading every
+// token, so a read from the underlying reader that blocks is not interrupted.
+//
+// After a cancellation, the Decoder is positioned somewhere inside the
+// element being decoded, and v may be partially filled.
+func (d *Decoder) DecodeContext(ctx context.Context, v any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.ctx = ctx
+	defer func() { d.ctx = nil }()
+	return d.DecodeElement(v, nil)
+}
+
+// DecodeTimeout works like [Decoder.DecodeContext] with a context that is
+// done once timeout has elapsed, so it returns [context.DeadlineExceeded] if
+// the element was not decoded in time.
+func (d *Decoder) DecodeTimeout(timeout time.Duration, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DecodeContext(ctx, v)
+}
+
 // DecodeElement works like [Unmarshal] except that it takes
 // a pointer to the start XML element to decode into v.
 // It is useful when a client reads some raw XML tokens itself
@@ -153,6 +179,12 @@ func (d *Decoder) DecodeElement(v any, start *StartElement) error {
 	if val.IsNil() {
 		return errors.New("nil pointer passed to Unmarshal")
 	}
+
+	// An Unmarshaler calling DecodeElement adds to the mismatches of the call
+	// that is decoding it.
+	if d.unmarshalDepth == 0 {
+		d.mismatches = nil
+	}
 	return d.unmarshal(val.Elem(), start, 0)
 }
 
@@ -222,10 +254,10 @@ func (d *Decoder) unmarshalInterface(val Unmarshaler, start *StartElement) error
 	return nil
 }
 
-// unmarshalTextInterface unmarshals a single XML element into val.
-// The chardata contained in the element (but not its children)
-// is passed to the text unmarshaler.
-func (d *Decoder) unmarshalTextInterface(val encoding.TextUnmarshaler) error {
+// unmarshalTextInterface unmarshals a single XML element, named name, into
+// val, the text unmarshaler of dst. The chardata contained in the element
+// (but not its children) is passed to the text unmarshaler.
+func (d *Decoder) unmarshalTextInterface(val encoding.TextUnmarshaler, dst reflect.Value, name string) error {
 	var buf []byte
 	depth := 1
 	for depth > 0 {
@@ -244,11 +276,24 @@ func (d *Decoder) unmarshalTextInterface(val encoding.TextUnmarshaler) error {
 			depth--
 		}
 	}
-	return val.UnmarshalText(buf)
+	return d.unmarshalText(dst, val, buf, name)
 }
 
 // unmarshalAttr unmarshals a single XML attribute into val.
 func (d *Decoder) unmarshalAttr(val reflect.Value, attr Attr) error {
+	if !attrTarget(val) {
+		// The type of the field cannot hold any attribute value.
+		if d.AllowTypeMismatch {
+			src := []byte(attr.Value)
+			d.saveMismatch(val, src, "@"+attr.Name.Local, textKind(src))
+			return nil
+		}
+		if val.Kind() == reflect.Interface {
+			// As copyValue does, rather than calling the method of a nil
+			// interface below.
+			return errors.New("cannot unmarshal into " + val.Type().String())
+		}
+	}
 	if val.Kind() == reflect.Pointer {
 		if val.IsNil() {
 			val.Set(reflect.New(val.Type().Elem()))
@@ -271,12 +316,12 @@ func (d *Decoder) unmarshalAttr(val reflect.Value, attr Attr) error {
 	if val.CanInterface() && val.Type().Implements(textUnmarshalerType) {
 		// This is an unmarshaler with a non-pointer receiver,
 		// so it's likely to be incorrect, but we do what we're told.
-		return val.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(attr.Value))
+		return d.unmarshalText(val, val.Interface().(encoding.TextUnmarshaler), []byte(attr.Value), "@"+attr.Name.Local)
 	}
 	if val.CanAddr() {
 		pv := val.Addr()
 		if pv.CanInterface() && pv.Type().Implements(textUnmarshalerType) {
-			return pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(attr.Value))
+			return d.unmarshalText(val, pv.Interface().(encoding.TextUnmarshaler), []byte(attr.Value), "@"+attr.Name.Local)
 		}
 	}
 
@@ -300,7 +345,36 @@ func (d *Decoder) unmarshalAttr(val reflect.Value, attr Attr) error {
 		return nil
 	}
 
-	return d.copyValue(val, []byte(attr.Value))
+	return d.copyValue(val, []byte(attr.Value), "@"+attr.Name.Local)
+}
+
+// attrTarget reports whether unmarshalAttr can store an attribute value in
+// val, rather than failing or, for a nil interface, panicking.
+func attrTarget(val reflect.Value) bool {
+	if val.Kind() == reflect.Interface {
+		t := val.Type()
+		return !val.IsNil() && (t.Implements(unmarshalerAttrType) || t.Implements(textUnmarshalerType))
+	}
+	return attrTargetType(val.Type())
+}
+
+// attrTargetType reports whether unmarshalAttr can store an attribute value
+// in a value of type t.
+func attrTargetType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pt := reflect.PointerTo(t)
+	if t == attrType || pt.Implements(unmarshalerAttrType) || pt.Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8 || attrTargetType(t.Elem())
+	}
+	return isScalarKind(t.Kind())
 }
 
 var (
@@ -335,6 +409,10 @@ func (d *Decoder) unmarshal(val reflect.Value, start *StartElement, depth int) e
 			}
 		}
 	}
+	// The start tag was the last tag read, its position is the one of the
+	// type mismatches of the element and of its attributes.
+	line, column := d.tagLine, d.tagColumn
+	d.valueLine, d.valueColumn = line, column
 
 	// Load value from interface, but only if the result will be
 	// usefully addressable.
@@ -366,13 +444,13 @@ func (d *Decoder) unmarshal(val reflect.Value, start *StartElement, depth int) e
 	}
 
 	if val.CanInterface() && val.Type().Implements(textUnmarshalerType) {
-		return d.unmarshalTextInterface(val.Interface().(encoding.TextUnmarshaler))
+		return d.unmarshalTextInterface(val.Interface().(encoding.TextUnmarshaler), val, start.Name.Local)
 	}
 
 	if val.CanAddr() {
 		pv := val.Addr()
 		if pv.CanInterface() && pv.Type().Implements(textUnmarshalerType) {
-			return d.unmarshalTextInterface(pv.Interface().(encoding.TextUnmarshaler))
+			return d.unmarshalTextInterface(pv.Interface().(encoding.TextUnmarshaler), val, start.Name.Local)
 		}
 	}
 
@@ -440,10 +518,13 @@ func (d *Decoder) unmarshal(val reflect.Value, start *StartElement, depth int) e
 		// Validate and assign element name.
 		if tinfo.xmlname != nil {
 			finfo := tinfo.xmlname
-			if finfo.name != "" && finfo.name != start.Name.Local {
+			badName := finfo.name != "" && finfo.name != start.Name.Local
+			badSpace := finfo.xmlns != "" && finfo.xmlns != start.Name.Space
+			if (badName || badSpace) && d.AllowNameMismatch {
+				d.saveNameMismatch(typ, start.Name)
+			} else if badName {
 				return UnmarshalError("expected element type <" + finfo.name + "> but have <" + start.Name.Local + ">")
-			}
-			if finfo.xmlns != "" && finfo.xmlns != start.Name.Space {
+			} else if badSpace {
 				e := "expected element <" + finfo.name + "> in name space " + finfo.xmlns + " but have "
 				if start.Name.Space == "" {
 					e += "no name space"
@@ -524,6 +605,10 @@ func (d *Decoder) unmarshal(val reflect.Value, start *StartElement, depth int) e
 
 	// Find end element.
 	// Process sub-elements along the way.
+	// hasChildren reports whether the element being decoded into a value
+	// that is not a struct has child elements.
+	hasChildren := false
+
 Loop:
 	for {
 		var savedOffset int
@@ -556,6 +641,9 @@ Loop:
 					return err
 				}
 			}
+			if !sv.IsValid() {
+				hasChildren = true
+			}
 
 		case EndElement:
 			if saveXML.IsValid() {
@@ -578,8 +666,9 @@ Loop:
 		}
 	}
 
+	d.valueLine, d.valueColumn = line, column
 	if saveData.IsValid() && saveData.CanInterface() && saveData.Type().Implements(textUnmarshalerType) {
-		if err := saveData.Interface().(encoding.TextUnmarshaler).UnmarshalText(data); err != nil {
+		if err := d.unmarshalText(saveData, saveData.Interface().(encoding.TextUnmarshaler), data, start.Name.Local); err != nil {
 			return err
 		}
 		saveData = reflect.Value{}
@@ -588,14 +677,21 @@ Loop:
 	if saveData.IsValid() && saveData.CanAddr() {
 		pv := saveData.Addr()
 		if pv.CanInterface() && pv.Type().Implements(textUnmarshalerType) {
-			if err := pv.Interface().(encoding.TextUnmarshaler).UnmarshalText(data); err != nil {
+			if err := d.unmarshalText(saveData, pv.Interface().(encoding.TextUnmarshaler), data, start.Name.Local); err != nil {
 				return err
 			}
 			saveData = reflect.Value{}
 		}
 	}
 
-	if err := d.copyValue(saveData, data); err != nil {
+	// A numeric or boolean value cannot hold child elements, so in that case
+	// the element is a type mismatch, whatever its character data.
+	if hasChildren && d.AllowTypeMismatch && isScalar(saveData) {
+		d.saveMismatch(saveData, data, start.Name.Local, KindChildren)
+		saveData = reflect.Value{}
+	}
+
+	if err := d.copyValue(saveData, data, start.Name.Local); err != nil {
 		return err
 	}
 
@@ -618,7 +714,26 @@ Loop:
 	return nil
 }
 
-func (d *Decoder) copyValue(dst reflect.Value, src []byte) (err error) {
+// isScalar reports whether v is a numeric or boolean value.
+func isScalar(v reflect.Value) bool {
+	return isScalarKind(v.Kind())
+}
+
+// isScalarKind reports whether k is the kind of a value that isScalar accepts.
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// copyValue stores src, the content of the element or attribute name, in dst.
+// Attribute names are prefixed with '@'.
+func (d *Decoder) copyValue(dst reflect.Value, src []byte, name string) (err error) {
 	dst0 := dst
 
 	if dst.Kind() == reflect.Pointer {
@@ -635,52 +750,56 @@ func (d *Decoder) copyValue(dst reflect.Value, src []byte) (err error) {
 	default:
 		return errors.New("cannot unmarshal into " + dst0.Type().String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if len(src) == 0 {
+		if len(src) == 0 && !d.EmptyElementIsMismatch {
 			dst.SetInt(0)
 			return nil
 		}
 		itmp, err := strconv.ParseInt(strings.TrimSpace(string(src)), 10, dst.Type().Bits())
 		if err != nil {
 			if d.AllowTypeMismatch {
+				d.saveMismatch(dst, src, name, textKind(src))
 				return nil
 			}
 			return err
 		}
 		dst.SetInt(itmp)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		if len(src) == 0 {
+		if len(src) == 0 && !d.EmptyElementIsMismatch {
 			dst.SetUint(0)
 			return nil
 		}
 		utmp, err := strconv.ParseUint(strings.TrimSpace(string(src)), 10, dst.Type().Bits())
 		if err != nil {
 			if d.AllowTypeMismatch {
+				d.saveMismatch(dst, src, name, textKind(src))
 				return nil
 			}
 			return err
 		}
 		dst.SetUint(utmp)
 	case reflect.Float32, reflect.Float64:
-		if len(src) == 0 {
+		if len(src) == 0 && !d.EmptyElementIsMismatch {
 			dst.SetFloat(0)
 			return nil
 		}
 		ftmp, err := strconv.ParseFloat(strings.TrimSpace(string(src)), dst.Type().Bits())
 		if err != nil {
 			if d.AllowTypeMismatch {
+				d.saveMismatch(dst, src, name, textKind(src))
 				return nil
 			}
 			return err
 		}
 		dst.SetFloat(ftmp)
 	case reflect.Bool:
-		if len(src) == 0 {
+		if len(src) == 0 && !d.EmptyElementIsMismatch {
 			dst.SetBool(false)
 			return nil
 		}
 		value, err := strconv.ParseBool(strings.TrimSpace(string(src)))
 		if err != nil {
 			if d.AllowTypeMismatch {
+				d.saveMismatch(dst, src, name, textKind(src))
 				return nil
 			}
 			return err
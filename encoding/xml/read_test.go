@@ -6,6 +6,7 @@ package xml
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"reflect"
@@ -1126,3 +1127,96 @@ func TestCVE202230633(t *testing.T) {
 	}
 	Unmarshal(bytes.Repeat([]byte("<a>"), 17_000_000), &example)
 }
+
+// cancelReader returns the data of r a byte at a time, and calls cancel
+// once n bytes have been read.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel func()
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		r.cancel()
+	}
+	r.n--
+	return r.r.Read(p[:1])
+}
+
+func TestDecodeContext(t *testing.T) {
+	type T struct {
+		Items []int `xml:"item"`
+	}
+	const item = "<item>1</item>"
+	input := "<t>" + strings.Repeat(item, 100) + "</t>"
+
+	var got T
+	if err := NewDecoder(strings.NewReader(input)).DecodeContext(context.Background(), &got); err != nil {
+		t.Fatalf("DecodeContext: %v", err)
+	}
+	if len(got.Items) != 100 {
+		t.Fatalf("DecodeContext: got %d items, want 100", len(got.Items))
+	}
+
+	// Cancel the context while the decoder is in the middle of the document.
+	ctx, cancel := context.WithCancel(context.Background())
+	dec := NewDecoder(&cancelReader{r: strings.NewReader(input), n: len("<t>") + 10*len(item), cancel: cancel})
+	got = T{}
+	if err := dec.DecodeContext(ctx, &got); err != context.Canceled {
+		t.Fatalf("DecodeContext: got error %v, want %v", err, context.Canceled)
+	}
+	if n := len(got.Items); n == 0 || n > 10 {
+		t.Fatalf("DecodeContext: decoded %d items before cancellation, want between 1 and 10", n)
+	}
+	if err := dec.DecodeContext(ctx, &got); err != context.Canceled {
+		t.Fatalf("DecodeContext with done context: got error %v, want %v", err, context.Canceled)
+	}
+
+	// The context is only used during DecodeContext.
+	if _, err := dec.Token(); err != nil {
+		t.Fatalf("Token after DecodeContext: %v", err)
+	}
+}
+
+func TestDecodeTimeout(t *testing.T) {
+	type T struct {
+		Items []int `xml:"item"`
+	}
+	const item = "<item>1</item>"
+	input := "<t>" + strings.Repeat(item, 100) + "</t>"
+
+	// A timeout that has already elapsed stops before reading.
+	dec := NewDecoder(strings.NewReader(input))
+	var got T
+	if err := dec.DecodeTimeout(0, &got); err != context.DeadlineExceeded {
+		t.Fatalf("DecodeTimeout: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if off := dec.InputOffset(); off != 0 {
+		t.Fatalf("InputOffset after DecodeTimeout = %d, want 0", off)
+	}
+
+	// With enough time, the whole element is decoded.
+	if err := dec.DecodeTimeout(time.Minute, &got); err != nil {
+		t.Fatalf("DecodeTimeout: %v", err)
+	}
+	if len(got.Items) != 100 {
+		t.Fatalf("DecodeTimeout: got %d items, want 100", len(got.Items))
+	}
+
+	// After a call canceled in the middle of the document, an elapsed
+	// timeout stops the next call before it reads more.
+	ctx, cancel := context.WithCancel(context.Background())
+	dec = NewDecoder(&cancelReader{r: strings.NewReader(input), n: len("<t>") + 10*len(item), cancel: cancel})
+	got = T{}
+	if err := dec.DecodeContext(ctx, &got); err != context.Canceled {
+		t.Fatalf("DecodeContext: got error %v, want %v", err, context.Canceled)
+	}
+	off := dec.InputOffset()
+	if err := dec.DecodeTimeout(0, &got); err != context.DeadlineExceeded {
+		t.Fatalf("DecodeTimeout: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := dec.InputOffset(); got != off {
+		t.Fatalf("InputOffset after DecodeTimeout = %d, want %d", got, off)
+	}
+}
@@ -13,6 +13,7 @@ package xml
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -203,9 +204,64 @@ type Decoder struct {
 	// AllowTypeMismatch when true, causes the Decoder to not return an error when the
 	// input contains a XML value that does not match the type of the destination value.
 	//
-	// The destination value remains unmodified if the types does not match.
+	// The destination value is set to its zero value if the types does not match.
+	// White space surrounding the text of a numeric or boolean value is not a
+	// mismatch: " 123 " decodes into an int as 123. The tolerated mismatches
+	// are reported by [Decoder.Mismatches] and to MismatchHandler.
+	//
+	// A slice is never mismatched as a whole: every element is appended to
+	// it, a mismatched one as its zero value, so a slice is left nil only if
+	// the input has no element for it. An element repeated for a field that
+	// is not a slice is decoded every time, and the last one wins: if it is
+	// mismatched, the field is set to its zero value even if the previous
+	// ones were not, and if it is not, the mismatches of the previous ones
+	// are still reported. The elements caught by a field with the ",any"
+	// option are tolerated as any other, and their mismatches have the
+	// names of the elements in their paths, as in "a>unknown>b".
 	AllowTypeMismatch bool
 
+	// EmptyElementIsMismatch, when true, makes an empty element or attribute
+	// value, as in <int></int> or <int/>, a type mismatch when decoded into a
+	// numeric or boolean value. By default it is decoded as the zero value,
+	// without a mismatch.
+	EmptyElementIsMismatch bool
+
+	// AllowNameMismatch, when true, causes the Decoder to decode an element
+	// into a struct whose XMLName field requires another name or name space,
+	// instead of returning an error, so that documents whose root element
+	// name varies can be decoded. The XMLName field is set to the name of the
+	// element, and the element is reported by [Decoder.Mismatches] and to
+	// MismatchHandler with cause [CauseNameMismatch]. It does not need
+	// AllowTypeMismatch.
+	AllowNameMismatch bool
+
+	// TimeLayouts, if non-nil, are the layouts, as for [time.Parse], that
+	// the Decoder tries in order to parse the content of an element or
+	// attribute decoded into a [time.Time] when it is not in the RFC 3339
+	// format, after trimming the white space around it. Content that no
+	// layout matches is a type mismatch with AllowTypeMismatch. If
+	// TimeLayouts is nil, content that is not a valid time.Time is returned
+	// as an error even with AllowTypeMismatch.
+	TimeLayouts []string
+
+	// MismatchHandler, if non-nil, is called with every type mismatch
+	// tolerated because of AllowTypeMismatch, as soon as it is found, and
+	// before it is added to those reported by [Decoder.Mismatches]. It is
+	// also called while [Decoder.DecodeElement] decodes an element found
+	// with [Decoder.Token], in which case the path of the mismatch starts
+	// from the root of the document, not from that element.
+	MismatchHandler func(m TypeMismatch)
+
+	// ReplaceInvalidUTF8, when true, causes the Decoder to replace each
+	// invalid UTF-8 sequence found in character data and attribute values
+	// with the Unicode replacement character U+FFFD, instead of returning a
+	// syntax error. The replacement is applied to the text produced by
+	// CharsetReader, if any, so that it does not interfere with inputs in
+	// another charset.
+	//
+	// It is false by default, as such input is not well-formed XML.
+	ReplaceInvalidUTF8 bool
+
 	r              io.ByteReader
 	t              TokenReader
 	buf            bytes.Buffer
@@ -221,7 +277,13 @@ type Decoder struct {
 	line           int
 	linestart      int64
 	offset         int64
+	tagLine        int // position of the '<' of the last tag read
+	tagColumn      int
+	valueLine      int // position of the element reported by saveMismatch
+	valueColumn    int
 	unmarshalDepth int
+	ctx            context.Context // set during DecodeContext
+	mismatches     []TypeMismatch
 }
 
 // NewDecoder creates a new XML parser reading from r.
@@ -283,6 +345,11 @@ func NewTokenDecoder(t TokenReader) *Decoder {
 func (d *Decoder) Token() (Token, error) {
 	var t Token
 	var err error
+	if d.ctx != nil {
+		if err := d.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
 	if d.stk != nil && d.stk.kind == stkEOF {
 		return nil, io.EOF
 	}
@@ -586,6 +653,7 @@ func (d *Decoder) rawToken() (Token, error) {
 		}
 		return CharData(data), nil
 	}
+	d.tagLine, d.tagColumn = d.line, int(d.offset-d.linestart)
 
 	if b, ok = d.mustgetc(); !ok {
 		return nil, d.err
@@ -1141,6 +1209,10 @@ Input:
 	data := d.buf.Bytes()
 	data = data[0 : len(data)-trunc]
 
+	if d.ReplaceInvalidUTF8 && !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte(string(utf8.RuneError)))
+	}
+
 	// Inspect each rune for being a disallowed character.
 	buf := data
 	for len(buf) > 0 {